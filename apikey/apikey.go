@@ -0,0 +1,186 @@
+package apikey
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Key is an API key as held in a Store. Only the hash of the key is held. The
+// claims are set on the context when the key is used to authenticate.
+type Key struct {
+	// Name of the key, typically the partner it was issued to.
+	Name string `json:"name"`
+
+	// Hash of the key, as returned by Hash.
+	Hash string `json:"hash"`
+
+	// Expires is the time the key expires.
+	Expires time.Time `json:"expires"`
+
+	// Claims set on the context when the key is used.
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+// Authenticator authenticates requests using an API key held in a header or
+// query parameter. An Authenticator can be used with an auth.Chain, or using
+// the Authenticate middleware.
+type Authenticator struct {
+	// Store holding the keys.
+	Store Store
+
+	// Header holding the key. Leave blank to not read keys from a header.
+	Header string
+
+	// Query parameter holding the key. Leave blank to not read keys from the
+	// query. Keys sent in the query are likely to end up in logs, so a
+	// header should be used where possible.
+	Query string
+}
+
+// Header is the default header used to send API keys.
+const Header = "X-API-Key"
+
+// Method is the authentication method used by an Authenticator.
+const Method = "apikey"
+
+// NameKey is the context key used to hold the name of the API key that
+// authenticated the request. It is included in Reporter log fields.
+const NameKey = middleware.APIKeyKey
+
+// API key errors.
+var (
+	ErrUnknownKey = errors.New("unknown API key")
+	ErrExpiredKey = errors.New("API key has expired")
+)
+
+// Generate a new API key with the given name, expiry, and claims. The key is
+// returned along with the Key to hold in a Store. The key itself is not held
+// anywhere and should be given to the key's owner.
+func Generate(name string, ttl time.Duration, claims map[string]any) (string, Key) {
+	key := tonic.GenerateID() + tonic.GenerateID()
+
+	return key, Key{Name: name, Hash: Hash(key), Expires: time.Now().Add(ttl), Claims: claims}
+}
+
+// Hash returns the hex encoded SHA-256 hash of the key.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAuthenticator returns an Authenticator reading keys from the default
+// header.
+func NewAuthenticator(store Store) *Authenticator {
+	return &Authenticator{Store: store, Header: Header}
+}
+
+// Method returns the authentication method used by the Authenticator.
+func (a *Authenticator) Method() string {
+	return Method
+}
+
+// Authenticate the request. The claims of the key are set on the context in the
+// same way as tonic.Signatory.Validate, with the key name used as the subject
+// if the claims don't have one. The key name is also set under NameKey.
+// tonic.ErrMissingToken is returned if the request doesn't have a key. Keys are
+// compared in constant time.
+func (a *Authenticator) Authenticate(c *gin.Context) error {
+	var value string
+
+	if a.Header != "" {
+		value = c.GetHeader(a.Header)
+	}
+
+	if value == "" && a.Query != "" {
+		value = c.Query(a.Query)
+	}
+
+	if value == "" {
+		return fmt.Errorf("failed to authenticate API key: %w", tonic.ErrMissingToken)
+	}
+
+	key, err := a.lookup(value)
+
+	if err != nil {
+		return fmt.Errorf("failed to authenticate API key: %w", err)
+	}
+
+	claims := jwt.MapClaims{tonic.SubjectClaim: key.Name}
+
+	for k, v := range key.Claims {
+		claims[k] = v
+	}
+
+	for k, v := range claims {
+		c.Set(k, v)
+	}
+
+	c.Set(tonic.PrincipalKey, claims)
+	c.Set(NameKey, key.Name)
+
+	return nil
+}
+
+// Authenticate returns middleware that authenticates requests using an API key
+// in the default header. Failure to authenticate will abort the middleware
+// chain and return http.StatusUnauthorized, with the reason passed to c.Error.
+func Authenticate(store Store) gin.HandlerFunc {
+	a := NewAuthenticator(store)
+
+	return func(c *gin.Context) {
+		if err := a.Authenticate(c); err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(err)
+			c.Abort()
+			c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// lookup the key with the given value. Every key is compared so the time taken
+// doesn't depend on which key matched.
+func (a *Authenticator) lookup(value string) (Key, error) {
+	var (
+		found Key
+		match int
+	)
+
+	keys, err := a.Store.Keys()
+
+	if err != nil {
+		return found, fmt.Errorf("failed to get API keys: %w", err)
+	}
+
+	hash := []byte(Hash(value))
+
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare(hash, []byte(key.Hash)) == 1 {
+			found = key
+			match = 1
+		}
+	}
+
+	switch {
+	case match == 0:
+		return found, ErrUnknownKey
+	case time.Now().After(found.Expires):
+		return found, fmt.Errorf("%w: %s", ErrExpiredKey, found.Name)
+	}
+
+	return found, nil
+}
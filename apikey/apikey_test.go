@@ -0,0 +1,163 @@
+package apikey_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/apikey"
+	"github.com/domdavis/tonic/auth"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/cookie"
+	"github.com/domdavis/tonic/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+type Failing struct{}
+
+func (f Failing) Keys() ([]apikey.Key, error) {
+	return nil, assert.AnError
+}
+
+func ExampleAuthenticate() {
+	key, stored := apikey.Generate("partner", time.Hour*24*365, map[string]any{
+		tonic.ScopeClaim: "orders:read",
+	})
+
+	router := gin.New()
+	router.Use(apikey.Authenticate(apikey.NewMemoryStore(stored)))
+	router.GET("/orders", tonic.Require("orders:read"), func(c *gin.Context) {
+		c.String(http.StatusOK, "orders for %s", tonic.Get[string](c, tonic.SubjectClaim))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(apikey.Header, key)
+	router.ServeHTTP(w, req)
+
+	fmt.Println(w.Code, w.Body.String())
+
+	// Output:
+	// 200 orders for partner
+}
+
+func TestAuthenticate(t *testing.T) {
+	key, stored := apikey.Generate("partner", time.Hour, map[string]any{"tier": "gold"})
+	_, other := apikey.Generate("other", time.Hour, nil)
+	expired, old := apikey.Generate("old", -time.Second, nil)
+	store := apikey.NewMemoryStore(other, old)
+	store.Add(stored)
+
+	serve := func(t *testing.T, handler gin.HandlerFunc, req *http.Request) (int, []*gin.Error) {
+		t.Helper()
+
+		var errs []*gin.Error
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Next()
+			errs = c.Errors
+		})
+		router.GET("/", handler, func(c *gin.Context) {
+			c.String(http.StatusOK, "%s %s", tonic.Get[string](c, "tier"), c.GetString(apikey.NameKey))
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w.Code, errs
+	}
+
+	t.Run("Valid keys authenticate", func(t *testing.T) {
+		t.Parallel()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(apikey.Header, key)
+
+		code, errs := serve(t, apikey.Authenticate(store), req)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("Invalid keys are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		for value, reason := range map[string]error{
+			"":        tonic.ErrMissingToken,
+			"garbage": apikey.ErrUnknownKey,
+			expired:   apikey.ErrExpiredKey,
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(apikey.Header, value)
+
+			code, errs := serve(t, apikey.Authenticate(store), req)
+
+			assert.Equal(t, http.StatusUnauthorized, code)
+			assert.Len(t, errs, 1)
+			assert.ErrorIs(t, errs[0], reason)
+		}
+	})
+
+	t.Run("Keys can be sent in the query", func(t *testing.T) {
+		t.Parallel()
+
+		a := &apikey.Authenticator{Store: store, Query: "key"}
+		router := gin.New()
+		router.GET("/", auth.Chain("", a), func(c *gin.Context) {
+			c.String(http.StatusOK, auth.Method(c))
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?key="+key, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, apikey.Method, w.Body.String())
+
+		// Headers are ignored if not configured.
+		w = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(apikey.Header, key)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Store failures are reported", func(t *testing.T) {
+		t.Parallel()
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(apikey.Header, key)
+
+		code, errs := serve(t, apikey.Authenticate(Failing{}), req)
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.ErrorIs(t, errs[0], assert.AnError)
+	})
+
+	t.Run("Key usage is reported", func(t *testing.T) {
+		t.Parallel()
+
+		instance, hook := test.NewNullLogger()
+		reporter := middleware.LogrusReporter(instance)
+
+		router := gin.New()
+		router.Use(reporter.Log)
+		router.GET("/", auth.Chain("",
+			cookie.NewAuthenticator(config.Security{Secret: "secret", SessionTTL: time.Hour}),
+			apikey.NewAuthenticator(store),
+		))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(apikey.Header, key)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "partner", hook.LastEntry().Data["API key"])
+		assert.Equal(t, apikey.Method, hook.LastEntry().Data["auth method"])
+	})
+}
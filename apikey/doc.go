@@ -0,0 +1,3 @@
+// Package apikey handles authentication with long lived API keys, such as those
+// issued to partner services. Only hashes of the keys are stored.
+package apikey
@@ -0,0 +1,108 @@
+package apikey
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// A Store holds the API keys that can be used to authenticate.
+type Store interface {
+	// Keys returns all the keys held in the store, including expired keys.
+	Keys() ([]Key, error)
+}
+
+// MemoryStore is a Store held in memory. A MemoryStore is safe for concurrent
+// use.
+type MemoryStore struct {
+	mu   sync.Mutex
+	keys []Key
+}
+
+// FileStore is a Store that reads keys from a JSON file holding a list of Key.
+// The file is read again whenever it is modified, so keys can be added and
+// removed without restarting the service. If the file cannot be read then the
+// last keys read are used. A FileStore is safe for concurrent use.
+type FileStore struct {
+	path     string
+	mu       sync.Mutex
+	keys     []Key
+	modified time.Time
+}
+
+// NewMemoryStore returns a MemoryStore holding the given keys.
+func NewMemoryStore(keys ...Key) *MemoryStore {
+	return &MemoryStore{keys: keys}
+}
+
+// Add a key to the store.
+func (m *MemoryStore) Add(key Key) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys = append(m.keys, key)
+}
+
+// Keys returns the keys held in the store.
+func (m *MemoryStore) Keys() ([]Key, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Key(nil), m.keys...), nil
+}
+
+// NewFileStore returns a FileStore reading keys from the given path. An error
+// is returned if the file cannot be read.
+func NewFileStore(path string) (*FileStore, error) {
+	f := &FileStore{path: path}
+
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Keys returns the keys held in the file, reading it again if it has been
+// modified since it was last read.
+func (f *FileStore) Keys() ([]Key, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_ = f.load()
+
+	return append([]Key(nil), f.keys...), nil
+}
+
+// load the keys from the file if it has been modified. The lock must be held
+// by the caller, except when the store is being created.
+func (f *FileStore) load() error {
+	info, err := os.Stat(f.path)
+
+	if err != nil {
+		return fmt.Errorf("failed to read API keys: %w", err)
+	}
+
+	if f.keys != nil && info.ModTime().Equal(f.modified) {
+		return nil
+	}
+
+	b, err := os.ReadFile(f.path)
+
+	if err != nil {
+		return fmt.Errorf("failed to read API keys: %w", err)
+	}
+
+	keys := []Key{}
+
+	if err = json.Unmarshal(b, &keys); err != nil {
+		return fmt.Errorf("failed to parse API keys %s: %w", f.path, err)
+	}
+
+	f.keys = keys
+	f.modified = info.ModTime()
+
+	return nil
+}
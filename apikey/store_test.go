@@ -0,0 +1,86 @@
+package apikey_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic/apikey"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Run("Keys are read again when the file changes", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "keys.json")
+		_, first := apikey.Generate("first", time.Hour, nil)
+		_, second := apikey.Generate("second", time.Hour, nil)
+
+		write := func(modified time.Time, keys ...apikey.Key) {
+			b, err := json.Marshal(keys)
+
+			assert.NoError(t, err)
+			assert.NoError(t, os.WriteFile(path, b, 0o600))
+			assert.NoError(t, os.Chtimes(path, modified, modified))
+		}
+
+		write(time.Now().Add(-time.Minute), first)
+
+		store, err := apikey.NewFileStore(path)
+
+		assert.NoError(t, err)
+
+		keys, err := store.Keys()
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"first"}, names(keys))
+
+		write(time.Now(), first, second)
+
+		keys, err = store.Keys()
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"first", "second"}, names(keys))
+
+		// The last keys read are kept if the file is broken.
+		assert.NoError(t, os.WriteFile(path, []byte("garbage"), 0o600))
+
+		keys, err = store.Keys()
+
+		assert.NoError(t, err)
+		assert.Len(t, keys, 2)
+	})
+
+	t.Run("Missing or invalid files cannot be loaded", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := apikey.NewFileStore(filepath.Join(t.TempDir(), "missing.json"))
+
+		assert.Error(t, err)
+
+		path := filepath.Join(t.TempDir(), "keys.json")
+
+		assert.NoError(t, os.WriteFile(path, []byte("garbage"), 0o600))
+
+		_, err = apikey.NewFileStore(path)
+
+		assert.Error(t, err)
+
+		_, err = apikey.NewFileStore(t.TempDir())
+
+		assert.Error(t, err)
+	})
+}
+
+func names(keys []apikey.Key) []string {
+	list := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		list = append(list, key.Name)
+	}
+
+	return list
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/cookie"
+	"github.com/domdavis/tonic/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// An Authenticator authenticates requests using a single method, such as a
+// cookie or bearer token. The cookie and jwt packages both provide an
+// Authenticator.
+type Authenticator interface {
+	// Method returns the name of the authentication method.
+	Method() string
+
+	// Authenticate the request, setting the claims of the principal on the
+	// context. An error wrapping tonic.ErrMissingToken must be returned if
+	// the request doesn't carry credentials for this method, and one wrapping
+	// tonic.ErrMisconfigured if the Authenticator's settings are invalid.
+	Authenticate(c *gin.Context) error
+}
+
+// A Challenger is an Authenticator that can describe why a request failed to
+// authenticate using the WWW-Authenticate header.
+type Challenger interface {
+	// Challenge returns the WWW-Authenticate header value for the error
+	// returned by Authenticate.
+	Challenge(err error) string
+}
+
+// MethodKey is the context key used to hold the name of the method that
+// authenticated the request. It is included in Reporter log fields.
+const MethodKey = middleware.MethodKey
+
+// ChallengeHeader is the header used to describe why a request failed to
+// authenticate.
+const ChallengeHeader = "WWW-Authenticate"
+
+// Chain returns middleware that tries each Authenticator in order, stopping at
+// the first to succeed and recording its method on the context under MethodKey.
+// If none succeed the middleware chain is aborted. Browser requests, which
+// accept text/html, are redirected to the given URL in the same way as
+// cookie.Authenticate, while other requests, or all requests if the redirect is
+// blank, return http.StatusUnauthorized along with a WWW-Authenticate header
+// from each Challenger. The reasons each Authenticator failed are passed to
+// c.Error, except when a browser without any credentials is redirected. If an
+// Authenticator returns tonic.ErrMisconfigured then the chain is aborted with
+// http.StatusInternalServerError.
+func Chain(redirect string, authenticators ...Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var (
+			errs    = make([]error, 0, len(authenticators))
+			missing = true
+		)
+
+		for _, a := range authenticators {
+			err := a.Authenticate(c)
+
+			if err == nil {
+				c.Set(MethodKey, a.Method())
+				c.Next()
+
+				return
+			}
+
+			if errors.Is(err, tonic.ErrMisconfigured) {
+				//nolint:errcheck // Gin is handling this for us.
+				_ = c.Error(fmt.Errorf("failed to authenticate: %s: %w", a.Method(), err))
+				c.Abort()
+				c.String(http.StatusInternalServerError,
+					http.StatusText(http.StatusInternalServerError))
+
+				return
+			}
+
+			missing = missing && errors.Is(err, tonic.ErrMissingToken)
+			errs = append(errs, fmt.Errorf("%s: %w", a.Method(), err))
+		}
+
+		if len(errs) == 0 {
+			errs = append(errs, tonic.ErrMissingToken)
+		}
+
+		browser := redirect != "" && strings.Contains(c.GetHeader("Accept"), "text/html")
+
+		if !(browser && missing) {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(fmt.Errorf("failed to authenticate: %w", errors.Join(errs...)))
+		}
+
+		c.Abort()
+
+		if browser {
+			c.Redirect(http.StatusTemporaryRedirect, cookie.ReturnTo(c, redirect))
+
+			return
+		}
+
+		for i, a := range authenticators {
+			if challenger, ok := a.(Challenger); ok {
+				c.Writer.Header().Add(ChallengeHeader, challenger.Challenge(errs[i]))
+			}
+		}
+
+		c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+	}
+}
+
+// Method returns the name of the method that authenticated the request, or an
+// empty string if the request has not been authenticated by a Chain.
+func Method(c *gin.Context) string {
+	return c.GetString(MethodKey)
+}
@@ -0,0 +1,193 @@
+package auth_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/auth"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/cookie"
+	"github.com/domdavis/tonic/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func Router(security config.Security, redirect string, errs *[]*gin.Error) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Next()
+
+		if errs != nil {
+			*errs = c.Errors
+		}
+	})
+	router.GET("/login", func(c *gin.Context) {
+		_ = cookie.Drop(c, security)
+	})
+	router.GET("/token", func(c *gin.Context) {
+		jwt.Sign(c, security)
+	})
+	router.GET("/whoami", auth.Chain(redirect,
+		cookie.NewAuthenticator(security), jwt.NewAuthenticator(security),
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, auth.Method(c))
+	})
+
+	return router
+}
+
+func Do(router *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func ExampleChain() {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+	router := Router(security, "/login", nil)
+
+	// Browsers use a cookie.
+	login := Do(router, httptest.NewRequest(http.MethodGet, "/login", nil))
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.AddCookie(login.Result().Cookies()[0])
+
+	fmt.Println(Do(router, req).Body.String())
+
+	// API clients use a bearer token.
+	token := Do(router, httptest.NewRequest(http.MethodGet, "/token", nil))
+	req = httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	jwt.Set(req, token.Body.String())
+
+	fmt.Println(Do(router, req).Body.String())
+
+	// Browsers without credentials are sent to log in.
+	req = httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	w := Do(router, req)
+
+	fmt.Println(w.Code, w.Header().Get("Location"))
+
+	// API clients without credentials are not.
+	w = Do(router, httptest.NewRequest(http.MethodGet, "/whoami", nil))
+
+	fmt.Println(w.Code, w.Header().Get("WWW-Authenticate"))
+
+	// Output:
+	// cookie
+	// bearer
+	// 307 /login?next=%2Fwhoami
+	// 401 Bearer
+}
+
+func TestChain(t *testing.T) {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+	t.Run("Later methods are tried when earlier methods fail", func(t *testing.T) {
+		t.Parallel()
+
+		var errs []*gin.Error
+
+		router := Router(security, "", &errs)
+		token := Do(router, httptest.NewRequest(http.MethodGet, "/token", nil)).Body.String()
+
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: "garbage"})
+		jwt.Set(req, token)
+
+		w := Do(router, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, jwt.Method, w.Body.String())
+		assert.Empty(t, errs)
+	})
+
+	t.Run("Failures are reported for each method", func(t *testing.T) {
+		t.Parallel()
+
+		var errs []*gin.Error
+
+		router := Router(security, "/login", &errs)
+
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: "garbage"})
+
+		w := Do(router, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, []string{"Bearer"}, w.Header().Values(auth.ChallengeHeader))
+		assert.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], tonic.ErrMalformed)
+		assert.ErrorIs(t, errs[0], tonic.ErrMissingToken)
+		assert.Contains(t, errs[0].Error(), "cookie: ")
+		assert.Contains(t, errs[0].Error(), "bearer: ")
+	})
+
+	t.Run("Browsers with invalid credentials are redirected", func(t *testing.T) {
+		t.Parallel()
+
+		var errs []*gin.Error
+
+		router := Router(security, "/login", &errs)
+
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set("Accept", "text/html")
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: "garbage"})
+
+		w := Do(router, req)
+
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], tonic.ErrMalformed)
+	})
+
+	t.Run("Browsers are not redirected without a redirect", func(t *testing.T) {
+		t.Parallel()
+
+		var errs []*gin.Error
+
+		router := Router(security, "", &errs)
+
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set("Accept", "text/html")
+
+		w := Do(router, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], tonic.ErrMissingToken)
+	})
+
+	t.Run("Empty chains never authenticate", func(t *testing.T) {
+		t.Parallel()
+
+		router := gin.New()
+		router.GET("/", auth.Chain(""))
+
+		w := Do(router, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Misconfigured methods fail", func(t *testing.T) {
+		t.Parallel()
+
+		var errs []*gin.Error
+
+		s := security
+		s.PrivateKey = "missing.pem"
+
+		router := Router(s, "", &errs)
+		w := Do(router, httptest.NewRequest(http.MethodGet, "/whoami", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Header().Values(auth.ChallengeHeader))
+		assert.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], tonic.ErrMisconfigured)
+		assert.Contains(t, errs[0].Error(), "invalid security settings")
+	})
+}
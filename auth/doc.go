@@ -0,0 +1,3 @@
+// Package auth combines authentication methods, allowing routes to be shared
+// between browsers and API clients.
+package auth
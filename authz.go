@@ -0,0 +1,109 @@
+package tonic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Authorization claim names. Scopes are held as a space separated string as
+// defined in RFC 8693, while roles are held as a list as defined in RFC 9068.
+// Either claim may use either form.
+const (
+	ScopeClaim = "scope"
+	RolesClaim = "roles"
+)
+
+// Authorization errors.
+var (
+	ErrUnauthenticated = errors.New("not authenticated")
+	ErrForbidden       = errors.New("permission denied")
+)
+
+// Require returns middleware that only allows requests from principals holding
+// all the given scopes.
+func Require(scopes ...string) gin.HandlerFunc {
+	return Authorize("scope "+strings.Join(scopes, " "), func(claims jwt.MapClaims) bool {
+		held := values(claims, ScopeClaim)
+
+		for _, scope := range scopes {
+			if !contains(held, scope) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// RequireAny returns middleware that only allows requests from principals
+// holding at least one of the given roles.
+func RequireAny(roles ...string) gin.HandlerFunc {
+	return Authorize("any role of "+strings.Join(roles, ", "), func(claims jwt.MapClaims) bool {
+		held := values(claims, RolesClaim)
+
+		for _, role := range roles {
+			if contains(held, role) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// Authorize returns middleware that only allows requests from principals whose
+// claims satisfy the allowed predicate. The middleware must be used after an
+// authentication middleware, which sets the claims of the principal on the
+// context under PrincipalKey. Requests without a principal will abort the
+// middleware chain and return http.StatusUnauthorized, while principals that
+// are not allowed will abort the chain and return http.StatusForbidden. The
+// permission describes what is being required, and is passed to c.Error when
+// a request is denied.
+func Authorize(permission string, allowed func(claims jwt.MapClaims) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := Principal[jwt.MapClaims](c)
+
+		switch {
+		case !ok:
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(fmt.Errorf("%w: %s requires a principal", ErrUnauthenticated, permission))
+			c.Abort()
+			c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		case !allowed(claims):
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(fmt.Errorf("%w: %s", ErrForbidden, permission))
+			c.Abort()
+			c.String(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		default:
+			c.Next()
+		}
+	}
+}
+
+// values returns the values held in the named claim, which may be either a
+// space separated string or a list of strings.
+func values(claims jwt.MapClaims, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []any:
+		list := make([]string, 0, len(v))
+
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+
+		return list
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,173 @@
+package tonic_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// Authenticated returns middleware that validates the token in the query.
+func Authenticated(s *tonic.Signatory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("token"); token != "" {
+			if err := s.Validate(c, token); err != nil {
+				c.AbortWithStatus(http.StatusUnauthorized)
+
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func ExampleRequire() {
+	s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+
+	router := gin.New()
+	router.Use(Authenticated(s))
+	router.GET("/orders", tonic.Require("orders:read"), func(c *gin.Context) {
+		c.String(http.StatusOK, "orders")
+	})
+	router.DELETE("/orders", tonic.Require("orders:read", "orders:write"), func(c *gin.Context) {
+		c.String(http.StatusOK, "deleted")
+	})
+
+	token, _ := s.Issue(jwt.MapClaims{tonic.ScopeClaim: "orders:read profile"})
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/orders?token="+token, nil))
+
+		fmt.Println(w.Code, w.Body.String())
+	}
+
+	// Output:
+	// 200 orders
+	// 403 Forbidden
+}
+
+func TestRequireAny(t *testing.T) {
+	s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+
+	serve := func(t *testing.T, handler gin.HandlerFunc, claims jwt.MapClaims) (int, []*gin.Error) {
+		t.Helper()
+
+		var errs []*gin.Error
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Next()
+			errs = c.Errors
+		})
+		router.Use(Authenticated(s))
+		router.GET("/", handler, func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+
+		path := "/"
+
+		if claims != nil {
+			token, err := s.Issue(claims)
+
+			assert.NoError(t, err)
+
+			path += "?token=" + token
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return w.Code, errs
+	}
+
+	t.Run("Any of the roles is enough", func(t *testing.T) {
+		t.Parallel()
+
+		code, errs := serve(t, tonic.RequireAny("admin", "editor"),
+			jwt.MapClaims{tonic.RolesClaim: []string{"viewer", "editor"}})
+
+		assert.Equal(t, http.StatusNoContent, code)
+		assert.Empty(t, errs)
+
+		code, _ = serve(t, tonic.RequireAny("admin"), jwt.MapClaims{tonic.RolesClaim: "viewer admin"})
+
+		assert.Equal(t, http.StatusNoContent, code)
+	})
+
+	t.Run("Missing roles are forbidden", func(t *testing.T) {
+		t.Parallel()
+
+		code, errs := serve(t, tonic.RequireAny("admin", "editor"),
+			jwt.MapClaims{tonic.RolesClaim: []any{"viewer", 1}})
+
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], tonic.ErrForbidden)
+		assert.Contains(t, errs[0].Error(), "admin, editor")
+
+		code, _ = serve(t, tonic.RequireAny("admin"), jwt.MapClaims{tonic.RolesClaim: 1})
+
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("Unauthenticated requests are unauthorised", func(t *testing.T) {
+		t.Parallel()
+
+		code, errs := serve(t, tonic.RequireAny("admin"), nil)
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], tonic.ErrUnauthenticated)
+	})
+
+	t.Run("Predicates can check any claim", func(t *testing.T) {
+		t.Parallel()
+
+		owner := tonic.Authorize("owner", func(claims jwt.MapClaims) bool {
+			return claims[tonic.SubjectClaim] == "owner"
+		})
+
+		code, _ := serve(t, owner, jwt.MapClaims{tonic.SubjectClaim: "owner"})
+
+		assert.Equal(t, http.StatusNoContent, code)
+
+		code, errs := serve(t, owner, jwt.MapClaims{tonic.SubjectClaim: "visitor"})
+
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.ErrorIs(t, errs[0], tonic.ErrForbidden)
+	})
+}
+
+func TestRequire(t *testing.T) {
+	t.Run("Typed principals can be authorised", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		token, err := tonic.Sign(s, User{Roles: []string{"admin"}})
+
+		assert.NoError(t, err)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			_, err := tonic.Validate[User](c, s, token)
+
+			assert.NoError(t, err)
+		})
+		router.GET("/", tonic.RequireAny("admin"), tonic.Require(), func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+}
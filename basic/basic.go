@@ -0,0 +1,101 @@
+package basic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Authenticator authenticates requests using HTTP Basic authentication against
+// an htpasswd file. An Authenticator can be used with an auth.Chain, or using
+// the Authenticate middleware.
+type Authenticator struct {
+	// Users allowed to authenticate.
+	Users *Htpasswd
+
+	// Realm sent in the WWW-Authenticate header.
+	Realm string
+
+	// Timebox is the minimum time a failed attempt will take, so failures
+	// take the same time regardless of why they failed.
+	Timebox time.Duration
+}
+
+// Method is the authentication method used by an Authenticator.
+const Method = "basic"
+
+// ErrInvalidCredentials is returned if the username or password are incorrect.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// NewAuthenticator returns an Authenticator for the given users and realm,
+// using the Timebox from the security settings.
+func NewAuthenticator(security config.Security, users *Htpasswd, realm string) *Authenticator {
+	return &Authenticator{Users: users, Realm: realm, Timebox: security.Timebox}
+}
+
+// Method returns the authentication method used by the Authenticator.
+func (a *Authenticator) Method() string {
+	return Method
+}
+
+// Authenticate the request. The username is set on the context under
+// tonic.SubjectClaim, and as the subject of the principal under
+// tonic.PrincipalKey. tonic.ErrMissingToken is returned if the request doesn't
+// use Basic authentication. Failures will not return until the Timebox has
+// passed.
+func (a *Authenticator) Authenticate(c *gin.Context) error {
+	user, password, ok := c.Request.BasicAuth()
+
+	if !ok {
+		return fmt.Errorf("failed to authenticate user: %w", tonic.ErrMissingToken)
+	}
+
+	deadline := tonic.Timebox(a.Timebox)
+
+	if !a.Users.Verify(user, password) {
+		deadline.Wait()
+
+		return fmt.Errorf("failed to authenticate %q: %w", user, ErrInvalidCredentials)
+	}
+
+	c.Set(tonic.SubjectClaim, user)
+	c.Set(tonic.PrincipalKey, jwt.MapClaims{tonic.SubjectClaim: user})
+
+	return nil
+}
+
+// Challenge returns the WWW-Authenticate header value asking for Basic
+// authentication.
+func (a *Authenticator) Challenge(error) string {
+	return fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", a.Realm)
+}
+
+// Authenticate returns middleware that authenticates requests using HTTP Basic
+// authentication against the given users. Failure to authenticate will abort
+// the middleware chain and return http.StatusUnauthorized, asking for Basic
+// authentication for the given realm. Failed attempts take at least the
+// Timebox from the security settings. The reason a request failed to
+// authenticate is passed to c.Error.
+func Authenticate(security config.Security, users *Htpasswd, realm string) gin.HandlerFunc {
+	a := NewAuthenticator(security, users, realm)
+
+	return func(c *gin.Context) {
+		if err := a.Authenticate(c); err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(err)
+			c.Abort()
+			c.Header("WWW-Authenticate", a.Challenge(err))
+			c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,103 @@
+package basic_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/auth"
+	"github.com/domdavis/tonic/basic"
+	"github.com/domdavis/tonic/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleAuthenticate() {
+	users, _ := basic.NewHtpasswd("testdata/htpasswd")
+	security := config.Security{Timebox: time.Millisecond}
+
+	router := gin.New()
+	router.GET("/admin", basic.Authenticate(security, users, "admin"), func(c *gin.Context) {
+		c.String(http.StatusOK, "hello %s", tonic.Get[string](c, tonic.SubjectClaim))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.SetBasicAuth("apr1", "apr1-password")
+	router.ServeHTTP(w, req)
+
+	fmt.Println(w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+
+	fmt.Println(w.Code, w.Header().Get("WWW-Authenticate"))
+
+	// Output:
+	// 200 hello apr1
+	// 401 Basic realm="admin", charset="UTF-8"
+}
+
+func TestAuthenticate(t *testing.T) {
+	users, err := basic.NewHtpasswd("testdata/htpasswd")
+
+	assert.NoError(t, err)
+
+	t.Run("Failures are timeboxed", func(t *testing.T) {
+		t.Parallel()
+
+		var errs []*gin.Error
+
+		security := config.Security{Timebox: time.Millisecond * 50}
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Next()
+			errs = c.Errors
+		})
+		router.GET("/", basic.Authenticate(security, users, "admin"))
+
+		for _, user := range []string{"sha", "missing"} {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.SetBasicAuth(user, "wrong")
+
+			start := time.Now()
+			router.ServeHTTP(w, req)
+
+			assert.GreaterOrEqual(t, time.Since(start), security.Timebox)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Len(t, errs, 1)
+			assert.ErrorIs(t, errs[0], basic.ErrInvalidCredentials)
+		}
+	})
+
+	t.Run("Basic authentication can be chained", func(t *testing.T) {
+		t.Parallel()
+
+		router := gin.New()
+		router.GET("/", auth.Chain("", basic.NewAuthenticator(config.Security{}, users, "admin")),
+			tonic.Authorize("sha user", func(claims jwt.MapClaims) bool {
+				return claims[tonic.SubjectClaim] == "sha"
+			}), func(c *gin.Context) {
+				c.String(http.StatusOK, auth.Method(c))
+			})
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth("sha", "sha-password")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, basic.Method, w.Body.String())
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Basic realm="admin", charset="UTF-8"`, w.Header().Get(auth.ChallengeHeader))
+	})
+}
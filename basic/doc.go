@@ -0,0 +1,2 @@
+// Package basic handles HTTP Basic authentication against an htpasswd file.
+package basic
@@ -0,0 +1,233 @@
+package basic
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"  //nolint:gosec // Required by the apr1 format.
+	"crypto/sha1" //nolint:gosec // Required by the SHA format.
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Htpasswd holds the users from an htpasswd file. Passwords hashed with bcrypt,
+// SHA, and apr1 are supported. The file is read again whenever it is modified,
+// so users can be added and removed without restarting the service. If the
+// file cannot be read then the last users read are used. An Htpasswd is safe
+// for concurrent use.
+type Htpasswd struct {
+	path     string
+	mu       sync.Mutex
+	users    map[string]string
+	modified time.Time
+}
+
+// ErrUnsupportedHash is returned if an htpasswd file holds a password hash that
+// isn't supported.
+var ErrUnsupportedHash = errors.New("unsupported password hash")
+
+// Password hash prefixes.
+const (
+	apr1Prefix = "$apr1$"
+	shaPrefix  = "{SHA}"
+)
+
+// NewHtpasswd returns an Htpasswd holding the users in the file at the given
+// path. An error is returned if the file cannot be read, or holds a password
+// hash that isn't supported.
+func NewHtpasswd(path string) (*Htpasswd, error) {
+	h := &Htpasswd{path: path}
+
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Verify returns true if the user is in the file and the password matches.
+func (h *Htpasswd) Verify(user, password string) bool {
+	h.mu.Lock()
+	_ = h.load()
+	hash, ok := h.users[user]
+	h.mu.Unlock()
+
+	return ok && verify(hash, password)
+}
+
+// load the users from the file if it has been modified. The lock must be held
+// by the caller, except when the Htpasswd is being created.
+func (h *Htpasswd) load() error {
+	info, err := os.Stat(h.path)
+
+	if err != nil {
+		return fmt.Errorf("failed to read htpasswd: %w", err)
+	}
+
+	if h.users != nil && info.ModTime().Equal(h.modified) {
+		return nil
+	}
+
+	b, err := os.ReadFile(h.path)
+
+	if err != nil {
+		return fmt.Errorf("failed to read htpasswd: %w", err)
+	}
+
+	users := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		user, hash, ok := strings.Cut(text, ":")
+
+		if !ok || user == "" {
+			return fmt.Errorf("failed to parse htpasswd %s: line %d is malformed", h.path, line)
+		}
+
+		if !supported(hash) {
+			return fmt.Errorf("%w: %s line %d", ErrUnsupportedHash, h.path, line)
+		}
+
+		users[user] = hash
+	}
+
+	h.users = users
+	h.modified = info.ModTime()
+
+	return nil
+}
+
+// supported returns true if the password hash is in a supported format.
+func supported(hash string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"),
+		strings.HasPrefix(hash, "$2y$"):
+		_, err := bcrypt.Cost([]byte(hash))
+
+		return err == nil
+	case strings.HasPrefix(hash, apr1Prefix):
+		salt, _, ok := strings.Cut(strings.TrimPrefix(hash, apr1Prefix), "$")
+
+		return ok && salt != ""
+	case strings.HasPrefix(hash, shaPrefix):
+		return true
+	default:
+		return false
+	}
+}
+
+// verify the password against the hash.
+func verify(hash, password string) bool {
+	var expected string
+
+	switch {
+	case strings.HasPrefix(hash, apr1Prefix):
+		salt, _, _ := strings.Cut(strings.TrimPrefix(hash, apr1Prefix), "$")
+		expected = apr1(password, salt)
+	case strings.HasPrefix(hash, shaPrefix):
+		sum := sha1.Sum([]byte(password)) //nolint:gosec // Required by the SHA format.
+		expected = shaPrefix + base64.StdEncoding.EncodeToString(sum[:])
+	default:
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(expected)) == 1
+}
+
+// apr1 returns the Apache MD5 crypt hash of the password using the given salt.
+func apr1(password, salt string) string {
+	const (
+		rounds     = 1000
+		maxSalt    = 8
+		blockSize  = md5.Size
+		bits       = 6
+		groupChars = 4
+		lastChars  = 2
+		lastByte   = 11
+		alphabet   = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	)
+
+	if len(salt) > maxSalt {
+		salt = salt[:maxSalt]
+	}
+
+	pw := []byte(password)
+	alt := md5.Sum([]byte(password + salt + password)) //nolint:gosec // Required by the apr1 format.
+	digest := md5.New()                                //nolint:gosec // Required by the apr1 format.
+	digest.Write([]byte(password + apr1Prefix + salt))
+
+	for i := len(pw); i > 0; i -= blockSize {
+		if i > blockSize {
+			digest.Write(alt[:])
+		} else {
+			digest.Write(alt[:i])
+		}
+	}
+
+	for i := len(pw); i > 0; i >>= 1 {
+		if i&1 == 1 {
+			digest.Write([]byte{0})
+		} else {
+			digest.Write(pw[:1])
+		}
+	}
+
+	final := digest.Sum(nil)
+
+	for i := 0; i < rounds; i++ {
+		round := md5.New() //nolint:gosec // Required by the apr1 format.
+
+		if i&1 == 1 {
+			round.Write(pw)
+		} else {
+			round.Write(final)
+		}
+
+		if i%3 != 0 {
+			round.Write([]byte(salt))
+		}
+
+		if i%7 != 0 {
+			round.Write(pw)
+		}
+
+		if i&1 == 1 {
+			round.Write(final)
+		} else {
+			round.Write(pw)
+		}
+
+		final = round.Sum(nil)
+	}
+
+	var encoded strings.Builder
+
+	encode := func(v uint, n int) {
+		for ; n > 0; n-- {
+			encoded.WriteByte(alphabet[v&(1<<bits-1)])
+			v >>= bits
+		}
+	}
+
+	for _, group := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		//nolint:gomnd // Shifting whole bytes.
+		encode(uint(final[group[0]])<<16|uint(final[group[1]])<<8|uint(final[group[2]]), groupChars)
+	}
+
+	encode(uint(final[lastByte]), lastChars)
+
+	return apr1Prefix + salt + "$" + encoded.String()
+}
@@ -0,0 +1,84 @@
+package basic_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic/basic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHtpasswd_Verify(t *testing.T) {
+	t.Run("Supported hashes can be verified", func(t *testing.T) {
+		t.Parallel()
+
+		users, err := basic.NewHtpasswd("testdata/htpasswd")
+
+		assert.NoError(t, err)
+
+		for _, user := range []string{"bcrypt", "sha", "apr1"} {
+			assert.True(t, users.Verify(user, user+"-password"), user)
+			assert.False(t, users.Verify(user, "password"), user)
+		}
+
+		assert.False(t, users.Verify("missing", "missing-password"))
+	})
+
+	t.Run("The file is read again when it changes", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "htpasswd")
+		past := time.Now().Add(-time.Minute)
+
+		assert.NoError(t, os.WriteFile(path, []byte("sha:{SHA}MNLW6wfRtawHZ/atRhQOJCUt398=\n"), 0o600))
+		assert.NoError(t, os.Chtimes(path, past, past))
+
+		users, err := basic.NewHtpasswd(path)
+
+		assert.NoError(t, err)
+		assert.True(t, users.Verify("sha", "sha-password"))
+		assert.False(t, users.Verify("apr1", "apr1-password"))
+
+		assert.NoError(t, os.WriteFile(path, []byte("apr1:$apr1$s4lt$Ijziu8DKwteFREjx1qShm0\n"), 0o600))
+
+		assert.False(t, users.Verify("sha", "sha-password"))
+		assert.True(t, users.Verify("apr1", "apr1-password"))
+
+		// The last users read are kept if the file is broken.
+		assert.NoError(t, os.WriteFile(path, []byte("garbage\n"), 0o600))
+		assert.True(t, users.Verify("apr1", "apr1-password"))
+	})
+
+	t.Run("Invalid files cannot be loaded", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+
+		for name, content := range map[string]string{
+			"malformed": "garbage\n",
+			"blank":     ":{SHA}MNLW6wfRtawHZ/atRhQOJCUt398=\n",
+			"crypt":     "user:rl0uE6bJH/7ZQ\n",
+			"bcrypt":    "user:$2y$garbage\n",
+			"apr1":      "user:$apr1$nosalt\n",
+			"plain":     "user:password\n",
+		} {
+			path := filepath.Join(dir, name)
+
+			assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			_, err := basic.NewHtpasswd(path)
+
+			assert.Error(t, err, name)
+		}
+
+		_, err := basic.NewHtpasswd(filepath.Join(dir, "missing"))
+
+		assert.Error(t, err)
+
+		_, err = basic.NewHtpasswd(dir)
+
+		assert.Error(t, err)
+	})
+}
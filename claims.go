@@ -0,0 +1,141 @@
+package tonic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Claim validation errors.
+var (
+	ErrInvalidClaim    = errors.New("invalid claim")
+	ErrExpired         = errors.New("token has expired")
+	ErrNotYetValid     = errors.New("token is not yet valid")
+	ErrInvalidIssuer   = errors.New("invalid issuer")
+	ErrInvalidAudience = errors.New("invalid audience")
+)
+
+// Registered claim names.
+const (
+	IssuerClaim    = "iss"
+	SubjectClaim   = "sub"
+	AudienceClaim  = "aud"
+	ExpiryClaim    = "exp"
+	NotBeforeClaim = "nbf"
+	IssuedAtClaim  = "iat"
+	IDClaim        = "jti"
+)
+
+// Private returns a copy of the claims with the registered claims that are set
+// by a Signatory removed.
+func Private(claims map[string]any) map[string]any {
+	private := make(map[string]any, len(claims))
+
+	for k, v := range claims {
+		switch k {
+		case IssuerClaim, AudienceClaim, ExpiryClaim, NotBeforeClaim,
+			IssuedAtClaim, IDClaim, GenerationClaim:
+		default:
+			private[k] = v
+		}
+	}
+
+	return private
+}
+
+// register sets the registered claims on a payload being signed at the given
+// time.
+func (s *Signatory) register(payload jwt.MapClaims, now time.Time) {
+	payload[ExpiryClaim] = now.Add(s.TTL).Unix()
+	payload[NotBeforeClaim] = now.Unix()
+	payload[IssuedAtClaim] = now.Unix()
+
+	if _, ok := payload[IDClaim]; !ok {
+		payload[IDClaim] = GenerateID()
+	}
+
+	if s.Issuer != "" {
+		payload[IssuerClaim] = s.Issuer
+	}
+
+	switch len(s.Audience) {
+	case 0:
+	case 1:
+		payload[AudienceClaim] = s.Audience[0]
+	default:
+		payload[AudienceClaim] = s.Audience
+	}
+}
+
+// verify the registered claims of a token at the given time.
+func (s *Signatory) verify(claims jwt.MapClaims, now time.Time) error {
+	expires, ok, err := timeClaim(claims, ExpiryClaim)
+
+	switch {
+	case err != nil:
+		return err
+	case !ok:
+		return fmt.Errorf("%w: %s is required", ErrInvalidClaim, ExpiryClaim)
+	case now.After(expires.Add(s.Leeway)):
+		return fmt.Errorf("%w: expired at %s", ErrExpired, expires.Format(time.RFC3339))
+	}
+
+	if notBefore, ok, err := timeClaim(claims, NotBeforeClaim); err != nil {
+		return err
+	} else if ok && now.Add(s.Leeway).Before(notBefore) {
+		return fmt.Errorf("%w: not before %s", ErrNotYetValid, notBefore.Format(time.RFC3339))
+	}
+
+	if issued, ok, err := timeClaim(claims, IssuedAtClaim); err != nil {
+		return err
+	} else if ok && now.Add(s.Leeway).Before(issued) {
+		return fmt.Errorf("%w: issued in the future at %s", ErrNotYetValid,
+			issued.Format(time.RFC3339))
+	}
+
+	if s.Issuer != "" && !claims.VerifyIssuer(s.Issuer, true) {
+		return fmt.Errorf("%w: %v", ErrInvalidIssuer, claims[IssuerClaim])
+	}
+
+	return s.verifyAudience(claims)
+}
+
+// verifyAudience ensures the token is intended for one of the Signatory's
+// audiences.
+func (s *Signatory) verifyAudience(claims jwt.MapClaims) error {
+	if len(s.Audience) == 0 {
+		return nil
+	}
+
+	for _, audience := range s.Audience {
+		if claims.VerifyAudience(audience, true) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %v", ErrInvalidAudience, claims[AudienceClaim])
+}
+
+// timeClaim returns the time held in a NumericDate claim, and whether the claim
+// was set. An error is returned if the claim is not a NumericDate.
+func timeClaim(claims jwt.MapClaims, name string) (time.Time, bool, error) {
+	switch v := claims[name].(type) {
+	case nil:
+		return time.Time{}, false, nil
+	case float64:
+		return time.Unix(int64(v), 0), true, nil
+	case json.Number:
+		n, err := v.Int64()
+
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: %s is not a date", ErrInvalidClaim, name)
+		}
+
+		return time.Unix(n, 0), true, nil
+	default:
+		return time.Time{}, false, fmt.Errorf("%w: %s is not a date", ErrInvalidClaim, name)
+	}
+}
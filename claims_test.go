@@ -0,0 +1,215 @@
+package tonic_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Mint(t *testing.T, method jwt.SigningMethod, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString([]byte("secret"))
+
+	assert.NoError(t, err)
+
+	return token
+}
+
+func ExampleSignatory_Validate() {
+	security := config.Security{
+		Secret:     "secret",
+		SessionTTL: time.Hour,
+		Issuer:     "https://auth.example.com",
+		Audience:   "billing, reporting",
+	}
+
+	billing, _ := tonic.NewSignatory(security)
+	token, _ := billing.Sign(&gin.Context{})
+
+	// A service with the same secret, but a different audience, will reject
+	// the token.
+	security.Audience = "shipping"
+	shipping, _ := tonic.NewSignatory(security)
+
+	fmt.Println(billing.Validate(&gin.Context{}, token))
+	fmt.Println(errors.Is(shipping.Validate(&gin.Context{}, token), tonic.ErrInvalidAudience))
+
+	// Output:
+	// <nil>
+	// true
+}
+
+func TestSignatory_Validate_claims(t *testing.T) {
+	now := time.Now()
+	hour := time.Hour
+
+	s, err := tonic.NewSignatory(config.Security{
+		Secret: "secret", Issuer: "issuer", Audience: "a, b", Leeway: time.Minute,
+	})
+
+	assert.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		method jwt.SigningMethod
+		claims jwt.MapClaims
+		err    error
+	}{
+		"A valid token is accepted": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{"exp": now.Add(hour).Unix(), "iss": "issuer", "aud": "b"},
+		},
+		"An audience list is accepted": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{"exp": now.Add(hour).Unix(), "iss": "issuer", "aud": []string{"c", "a"}},
+		},
+		"Expired tokens within the leeway are accepted": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{"exp": now.Add(-time.Second).Unix(), "iss": "issuer", "aud": "a"},
+		},
+		"Expired tokens are rejected": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{"exp": now.Add(-hour).Unix(), "iss": "issuer", "aud": "a"},
+			err:    tonic.ErrExpired,
+		},
+		"Tokens without an expiry are rejected": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{"iss": "issuer", "aud": "a"},
+			err:    tonic.ErrInvalidClaim,
+		},
+		"Tokens with an invalid expiry are rejected": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{"exp": "tomorrow", "iss": "issuer", "aud": "a"},
+			err:    tonic.ErrInvalidClaim,
+		},
+		"Tokens that are not yet valid are rejected": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{
+				"exp": now.Add(hour).Unix(), "nbf": now.Add(hour).Unix(), "iss": "issuer", "aud": "a",
+			},
+			err: tonic.ErrNotYetValid,
+		},
+		"Tokens with an invalid nbf are rejected": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{"exp": now.Add(hour).Unix(), "nbf": "now", "iss": "issuer", "aud": "a"},
+			err:    tonic.ErrInvalidClaim,
+		},
+		"Tokens issued in the future are rejected": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{
+				"exp": now.Add(hour).Unix(), "iat": now.Add(hour).Unix(), "iss": "issuer", "aud": "a",
+			},
+			err: tonic.ErrNotYetValid,
+		},
+		"Tokens with an invalid iat are rejected": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{"exp": now.Add(hour).Unix(), "iat": true, "iss": "issuer", "aud": "a"},
+			err:    tonic.ErrInvalidClaim,
+		},
+		"Tokens from another issuer are rejected": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{"exp": now.Add(hour).Unix(), "iss": "other", "aud": "a"},
+			err:    tonic.ErrInvalidIssuer,
+		},
+		"Tokens for another audience are rejected": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{"exp": now.Add(hour).Unix(), "iss": "issuer", "aud": "c"},
+			err:    tonic.ErrInvalidAudience,
+		},
+		"Tokens without an audience are rejected": {
+			method: jwt.SigningMethodHS512,
+			claims: jwt.MapClaims{"exp": now.Add(hour).Unix(), "iss": "issuer"},
+			err:    tonic.ErrInvalidAudience,
+		},
+		"Tokens using a different algorithm are rejected": {
+			method: jwt.SigningMethodHS256,
+			claims: jwt.MapClaims{"exp": now.Add(hour).Unix(), "iss": "issuer", "aud": "a"},
+			err:    tonic.ErrWrongAlgorithm,
+		},
+	} {
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := s.Validate(&gin.Context{}, Mint(t, tc.method, tc.claims))
+
+			if tc.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestSignatory_Validate_methods(t *testing.T) {
+	t.Run("Algorithms not in the allow list are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := tonic.NewSignatory(config.Security{
+			Secret: "secret", SessionTTL: time.Hour, Algorithms: "RS256, ES256",
+		})
+
+		assert.NoError(t, err)
+
+		token, err := s.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+		assert.Error(t, s.Validate(&gin.Context{}, token))
+
+		s.Methods = []string{"HS512"}
+
+		assert.NoError(t, s.Validate(&gin.Context{}, token))
+	})
+}
+
+func TestSignatory_Sign_claims(t *testing.T) {
+	t.Run("Registered claims are set", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := tonic.NewSignatory(config.Security{
+			Secret: "secret", SessionTTL: time.Hour, Issuer: "issuer", Audience: "a",
+		})
+
+		assert.NoError(t, err)
+
+		token, err := s.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+
+		ctx := &gin.Context{}
+
+		assert.NoError(t, s.Validate(ctx, token))
+		assert.Equal(t, "issuer", tonic.Get[string](ctx, tonic.IssuerClaim))
+		assert.Equal(t, "a", tonic.Get[string](ctx, tonic.AudienceClaim))
+		assert.NotZero(t, tonic.Get[float64](ctx, tonic.IssuedAtClaim))
+		assert.NotZero(t, tonic.Get[float64](ctx, tonic.NotBeforeClaim))
+	})
+
+	t.Run("Multiple audiences are set as a list", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := tonic.NewSignatory(config.Security{
+			Secret: "secret", SessionTTL: time.Hour, Audience: "a, b",
+		})
+
+		assert.NoError(t, err)
+
+		token, err := s.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+
+		ctx := &gin.Context{}
+
+		assert.NoError(t, s.Validate(ctx, token))
+		assert.Equal(t, []any{"a", "b"}, tonic.Get[[]any](ctx, tonic.AudienceClaim))
+	})
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/domdavis/gofigure"
+)
+
+// Cookie settings.
+type Cookie struct {
+	// Name of the session cookie. Leave blank to use the default name.
+	Name string
+
+	// Path the cookie is valid for. Defaults to "/".
+	Path string
+
+	// SameSite mode for the cookie, one of lax, strict, or none. Leave blank
+	// to not set the SameSite attribute.
+	SameSite string
+
+	// Prefix for the cookie name, either host for the __Host- prefix or secure
+	// for the __Secure- prefix. Leave blank to not use a prefix.
+	Prefix string
+
+	// Secure forces the Secure attribute to be set on the cookie, even if the
+	// security Domain is blank or localhost.
+	Secure bool
+}
+
+// SameSite modes.
+const (
+	SameSiteLax    = "lax"
+	SameSiteStrict = "strict"
+	SameSiteNone   = "none"
+)
+
+// Cookie prefixes.
+const (
+	HostPrefix   = "host"
+	SecurePrefix = "secure"
+)
+
+// ErrInvalidCookie is returned if the cookie settings are invalid.
+var ErrInvalidCookie = errors.New("invalid cookie settings")
+
+// Register the Cookie options.
+func (c *Cookie) Register(conf *gofigure.Configuration) {
+	group := conf.Group("Cookie settings")
+
+	group.Add(gofigure.Optional("Cookie Name", "cookie-name", &c.Name, "",
+		gofigure.NamedSources, gofigure.HideUnset,
+		"Name of the session cookie, leave blank to use the default"))
+	group.Add(gofigure.Optional("Cookie Path", "cookie-path", &c.Path, "/",
+		gofigure.NamedSources, gofigure.ReportValue,
+		"Path the session cookie is valid for"))
+	group.Add(gofigure.Optional("Cookie SameSite", "cookie-same-site",
+		&c.SameSite, SameSiteLax, gofigure.NamedSources, gofigure.ReportValue,
+		"SameSite mode for cookies: lax, strict, or none"))
+	group.Add(gofigure.Optional("Cookie Prefix", "cookie-prefix", &c.Prefix, "",
+		gofigure.NamedSources, gofigure.HideUnset,
+		"Cookie name prefix: host for __Host-, secure for __Secure-, or blank for none"))
+	group.Add(gofigure.Optional("Secure Cookie", "cookie-secure", &c.Secure,
+		false, gofigure.Flag, gofigure.ReportValue,
+		"Always set the Secure attribute on cookies"))
+}
+
+// Named returns the name of the cookie, using the given name if no Name is
+// set, with the prefix added.
+func (c *Cookie) Named(name string) string {
+	if c.Name != "" {
+		name = c.Name
+	}
+
+	return c.Prefixed(name)
+}
+
+// Prefixed returns the given name with the prefix added.
+func (c *Cookie) Prefixed(name string) string {
+	switch strings.ToLower(c.Prefix) {
+	case HostPrefix:
+		return "__Host-" + name
+	case SecurePrefix:
+		return "__Secure-" + name
+	default:
+		return name
+	}
+}
+
+// Mode returns the SameSite mode for the cookie. http.SameSiteDefaultMode is
+// returned if SameSite is blank or invalid.
+func (c *Cookie) Mode() http.SameSite {
+	switch strings.ToLower(c.SameSite) {
+	case SameSiteLax:
+		return http.SameSiteLaxMode
+	case SameSiteStrict:
+		return http.SameSiteStrictMode
+	case SameSiteNone:
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteDefaultMode
+	}
+}
+
+// Scope returns the path the cookie is valid for.
+func (c *Cookie) Scope() string {
+	if c.Path == "" {
+		return "/"
+	}
+
+	return c.Path
+}
+
+// Validate the cookie settings for a cookie set on the given domain, with the
+// Secure attribute set as given. The __Secure- prefix and SameSite=None both
+// require the Secure attribute, while the __Host- prefix also requires the
+// Path to be "/" and the Domain to be blank.
+func (c *Cookie) Validate(domain string, secure bool) error {
+	if c.Mode() == http.SameSiteDefaultMode && c.SameSite != "" {
+		return fmt.Errorf("%w: unknown SameSite mode %q", ErrInvalidCookie, c.SameSite)
+	}
+
+	switch prefix := strings.ToLower(c.Prefix); {
+	case prefix != "" && prefix != HostPrefix && prefix != SecurePrefix:
+		return fmt.Errorf("%w: unknown prefix %q", ErrInvalidCookie, c.Prefix)
+	case prefix != "" && !secure:
+		return fmt.Errorf("%w: prefixed cookies must be secure", ErrInvalidCookie)
+	case prefix == HostPrefix && c.Scope() != "/":
+		return fmt.Errorf("%w: __Host- cookies must use the path /", ErrInvalidCookie)
+	case prefix == HostPrefix && domain != "":
+		return fmt.Errorf("%w: __Host- cookies cannot set a domain", ErrInvalidCookie)
+	case c.Mode() == http.SameSiteNoneMode && !secure:
+		return fmt.Errorf("%w: SameSite=None cookies must be secure", ErrInvalidCookie)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package config_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/domdavis/tonic/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleCookie_Named() {
+	c := config.Cookie{}
+
+	fmt.Println(c.Named("Session"))
+
+	c.Name = "App"
+	c.Prefix = config.HostPrefix
+
+	fmt.Println(c.Named("Session"))
+	fmt.Println(c.Prefixed("CSRF"))
+
+	c.Prefix = config.SecurePrefix
+
+	fmt.Println(c.Named("Session"))
+
+	// Output:
+	// Session
+	// __Host-App
+	// __Host-CSRF
+	// __Secure-App
+}
+
+func TestCookie_Mode(t *testing.T) {
+	t.Parallel()
+
+	for mode, expected := range map[string]http.SameSite{
+		"":                    http.SameSiteDefaultMode,
+		"lax":                 http.SameSiteLaxMode,
+		"None":                http.SameSiteNoneMode,
+		"bad":                 http.SameSiteDefaultMode,
+		config.SameSiteStrict: http.SameSiteStrictMode,
+	} {
+		c := config.Cookie{SameSite: mode}
+
+		assert.Equal(t, expected, c.Mode(), mode)
+	}
+}
+
+func TestCookie_Validate(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		cookie config.Cookie
+		domain string
+		secure bool
+		valid  bool
+	}{
+		"Defaults are valid": {valid: true},
+		"Unknown SameSite modes are invalid": {
+			cookie: config.Cookie{SameSite: "loose"},
+		},
+		"Unknown prefixes are invalid": {
+			cookie: config.Cookie{Prefix: "__Host-"},
+			secure: true,
+		},
+		"Prefixed cookies must be secure": {
+			cookie: config.Cookie{Prefix: config.SecurePrefix},
+		},
+		"Secure prefixes can set a domain and path": {
+			cookie: config.Cookie{Prefix: config.SecurePrefix, Path: "/app"},
+			domain: "example.com",
+			secure: true,
+			valid:  true,
+		},
+		"Host prefixes must use the root path": {
+			cookie: config.Cookie{Prefix: config.HostPrefix, Path: "/app"},
+			secure: true,
+		},
+		"Host prefixes cannot set a domain": {
+			cookie: config.Cookie{Prefix: config.HostPrefix},
+			domain: "example.com",
+			secure: true,
+		},
+		"Host prefixes are valid without a domain": {
+			cookie: config.Cookie{Prefix: config.HostPrefix, Path: "/"},
+			secure: true,
+			valid:  true,
+		},
+		"SameSite none must be secure": {
+			cookie: config.Cookie{SameSite: config.SameSiteNone},
+		},
+		"SameSite none is valid when secure": {
+			cookie: config.Cookie{SameSite: config.SameSiteNone},
+			secure: true,
+			valid:  true,
+		},
+	} {
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.cookie.Validate(tc.domain, tc.secure)
+
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, config.ErrInvalidCookie)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package config
+
+import "strings"
+
+// List splits a comma separated configuration value into its elements.
+// Whitespace around each element is removed and empty elements are dropped.
+func List(value string) []string {
+	var list []string
+
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			list = append(list, element)
+		}
+	}
+
+	return list
+}
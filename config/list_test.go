@@ -0,0 +1,16 @@
+package config_test
+
+import (
+	"fmt"
+
+	"github.com/domdavis/tonic/config"
+)
+
+func ExampleList() {
+	fmt.Printf("%q\n", config.List("a, b,,c "))
+	fmt.Println(len(config.List("")))
+
+	// Output:
+	// ["a" "b" "c"]
+	// 0
+}
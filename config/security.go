@@ -11,6 +11,68 @@ type Security struct {
 	// Secret used to encrypt JWT tokens.
 	Secret string
 
+	// SecretFile is the path to a file holding the secret, such as a Docker
+	// or Kubernetes secret. If set, it's used in place of the Secret and
+	// watched for changes, with the previous secret being retired when it
+	// changes.
+	SecretFile string
+
+	// StrictSecret causes an error if a random secret would be used while Gin
+	// is in release mode. Random secrets only work for a single instance of a
+	// service, and invalidate all tokens on restart.
+	StrictSecret bool
+
+	// PrivateKey is the path to a PEM encoded RSA, ECDSA, or Ed25519 private
+	// key. If set, tokens are signed with the key rather than the Secret.
+	PrivateKey string
+
+	// PublicKey is the path to a PEM encoded public key used to validate
+	// tokens. If unset, the public half of the PrivateKey is used. Services
+	// that only validate tokens can set PublicKey without a PrivateKey.
+	PublicKey string
+
+	// RetiredSecrets is a comma separated list of secrets that are no longer
+	// used to sign tokens, but which are still accepted when validating them.
+	// Retired secrets are accepted until the SessionTTL has passed since the
+	// service started, so should be removed once every instance has been
+	// running for that long.
+	RetiredSecrets string
+
+	// RetiredKeys is a comma separated list of paths to PEM encoded public
+	// keys that are no longer used to sign tokens, but which are still
+	// accepted when validating them. Retired keys are accepted until the
+	// SessionTTL has passed since the service started, in the same way as
+	// RetiredSecrets.
+	RetiredKeys string
+
+	// Algorithms is a comma separated list of the signing algorithms accepted
+	// when validating tokens. Leave blank to only accept the algorithm of the
+	// configured key.
+	Algorithms string
+
+	// Issuer set on, and required for, tokens. Leave blank to not use an
+	// issuer.
+	Issuer string
+
+	// Audience is a comma separated list of audiences set on tokens. Validated
+	// tokens must be intended for at least one of the audiences. Leave blank
+	// to not use an audience.
+	Audience string
+
+	// Leeway allowed for clock skew when checking token times.
+	Leeway time.Duration
+
+	// SharedTokens allows session cookies to be used as bearer tokens, and
+	// bearer tokens to be used as session cookies. By default each is
+	// rejected by the other's authenticator.
+	SharedTokens bool
+
+	// EncryptSessions encrypts session cookies so the claims they hold cannot
+	// be read by the client. Bearer tokens are never encrypted. Sessions are
+	// encrypted using the secret, so a Secret or SecretFile must also be set
+	// when signing with a PrivateKey.
+	EncryptSessions bool
+
 	// Domain this service is running on.
 	Domain string
 
@@ -18,9 +80,20 @@ type Security struct {
 	// it requires re-authentication.
 	SessionTTL time.Duration
 
+	// SessionRefresh is the fraction of the SessionTTL after which a session
+	// cookie will be re-issued with a fresh TTL, keeping active users logged
+	// in. A value of 0 disables refreshing, giving sessions a hard expiry.
+	SessionRefresh float64
+
+	// RefreshTTL is the length of time a refresh token is valid for.
+	RefreshTTL time.Duration
+
 	// Timebox is the minimum time it will take for a login attempt to
 	// return.
 	Timebox time.Duration
+
+	// Cookie settings used for session cookies.
+	Cookie Cookie
 }
 
 // RandomSecret is used to tell tonic to use a random secret. The secret will
@@ -29,7 +102,9 @@ const RandomSecret = "<random>"
 
 const (
 	defaultSessionTTL = time.Hour * 12
+	defaultRefreshTTL = time.Hour * 24 * 30
 	defaultTimebox    = time.Millisecond * 500
+	defaultLeeway     = time.Second * 30
 )
 
 // Register the Security options.
@@ -39,18 +114,69 @@ func (s *Security) Register(c *gofigure.Configuration) {
 	group.Add(gofigure.Optional("JWT Secret", "secret",
 		&s.Secret, RandomSecret, gofigure.NamedSources, gofigure.MaskSet,
 		"Secret used to encrypt tokens. The default is to use a random secret."))
+	group.Add(gofigure.Optional("JWT Secret File", "secret-file",
+		&s.SecretFile, "", gofigure.NamedSources, gofigure.HideUnset,
+		"Path to a file holding the secret, which is watched for changes"))
+	group.Add(gofigure.Optional("Strict Secret", "strict-secret",
+		&s.StrictSecret, false, gofigure.Flag, gofigure.ReportValue,
+		"Fail to start in release mode if a random secret would be used"))
+	group.Add(gofigure.Optional("JWT Private Key", "private-key",
+		&s.PrivateKey, "", gofigure.NamedSources, gofigure.MaskUnset,
+		"Path to a PEM private key used to sign tokens in place of the secret"))
+	group.Add(gofigure.Optional("JWT Public Key", "public-key",
+		&s.PublicKey, "", gofigure.NamedSources, gofigure.MaskUnset,
+		"Path to a PEM public key used to validate tokens"))
+	group.Add(gofigure.Optional("Retired JWT Secrets", "retired-secrets",
+		&s.RetiredSecrets, "", gofigure.NamedSources, gofigure.MaskValue,
+		"Comma separated list of previous secrets still accepted for validation"))
+	group.Add(gofigure.Optional("Retired JWT Keys", "retired-keys",
+		&s.RetiredKeys, "", gofigure.NamedSources, gofigure.HideUnset,
+		"Comma separated list of previous public key paths still accepted for validation"))
+	group.Add(gofigure.Optional("JWT Algorithms", "algorithms",
+		&s.Algorithms, "", gofigure.NamedSources, gofigure.HideUnset,
+		"Comma separated list of accepted signing algorithms. Defaults to the key's algorithm"))
+	group.Add(gofigure.Optional("JWT Issuer", "issuer", &s.Issuer, "",
+		gofigure.NamedSources, gofigure.HideUnset,
+		"Issuer set on, and required for, tokens"))
+	group.Add(gofigure.Optional("JWT Audience", "audience", &s.Audience, "",
+		gofigure.NamedSources, gofigure.HideUnset,
+		"Comma separated list of audiences set on, and required for, tokens"))
+	group.Add(gofigure.Optional("JWT Leeway", "leeway", &s.Leeway,
+		defaultLeeway, gofigure.NamedSources, gofigure.ReportValue,
+		"Leeway allowed for clock skew when checking token times"))
+	group.Add(gofigure.Optional("Shared Tokens", "shared-tokens",
+		&s.SharedTokens, false, gofigure.Flag, gofigure.ReportValue,
+		"Allow session cookies and bearer tokens to be used interchangeably"))
+	group.Add(gofigure.Optional("Encrypt Sessions", "encrypt-sessions",
+		&s.EncryptSessions, false, gofigure.Flag, gofigure.ReportValue,
+		"Encrypt session cookies so their claims cannot be read"))
 	group.Add(gofigure.Optional("Cookie Domain", "domain", &s.Domain, "",
 		gofigure.NamedSources, gofigure.MaskUnset,
 		"Cookie domain, leave blank to allow insecure cookies"))
 	group.Add(gofigure.Optional("Session TTL", "session-ttl", &s.SessionTTL,
 		defaultSessionTTL, gofigure.NamedSources, gofigure.ReportValue,
 		"TTL for sessions"))
+	group.Add(gofigure.Optional("Session Refresh", "session-refresh",
+		&s.SessionRefresh, 0, gofigure.NamedSources, gofigure.ReportValue,
+		"Fraction of the session TTL after which a session is renewed, 0 to disable"))
+	group.Add(gofigure.Optional("Refresh TTL", "refresh-ttl", &s.RefreshTTL,
+		defaultRefreshTTL, gofigure.NamedSources, gofigure.ReportValue,
+		"TTL for refresh tokens"))
 	group.Add(gofigure.Optional("Login Timebox", "login-timebox", &s.Timebox,
 		defaultTimebox, gofigure.NamedSources, gofigure.ReportValue,
 		"Minimum time it will take for a login attempt to return"))
+
+	s.Cookie.Register(c)
 }
 
 // Secure returns true if a Domain is set and isn't localhost.
 func (s *Security) Secure() bool {
 	return s.Domain != "" && s.Domain != "localhost"
 }
+
+// SecureCookies returns true if cookies should be set with the Secure
+// attribute, either because the settings are Secure, or because the Cookie
+// settings require it.
+func (s *Security) SecureCookies() bool {
+	return s.Secure() || s.Cookie.Secure
+}
@@ -34,23 +34,93 @@ func ExampleSecurity_Register() {
 
 	// Unordered Output:
 	// Security settings
+	//   JWT Private Key: UNSET
+	//   JWT Public Key: UNSET
+	//   Retired JWT Secrets: UNSET
+	//   JWT Leeway: 30s
+	//   Shared Tokens: false
+	//   Encrypt Sessions: false
+	//   Strict Secret: false
 	//   Cookie Domain: UNSET
 	//   Session TTL: 12h0m0s
+	//   Session Refresh: 0
+	//   Refresh TTL: 720h0m0s
 	//   Login Timebox: 1s
 	//   JWT Secret: SET
+	// Cookie settings
+	//   Cookie Path: /
+	//   Cookie SameSite: lax
+	//   Secure Cookie: false
 	//
 	// usage:
 	//   JWT Secret [JSON key: "secret", env SECRET, --secret]
 	//     Secret used to encrypt tokens. The default is to use a random secret. (default: <random>)
 	//
+	//   JWT Secret File [JSON key: "secret-file", env SECRET_FILE, --secret-file]
+	//     Path to a file holding the secret, which is watched for changes
+	//
+	//   Strict Secret [--strict-secret]
+	//     Fail to start in release mode if a random secret would be used (default: false)
+	//
+	//   JWT Private Key [JSON key: "private-key", env PRIVATE_KEY, --private-key]
+	//     Path to a PEM private key used to sign tokens in place of the secret
+	//
+	//   JWT Public Key [JSON key: "public-key", env PUBLIC_KEY, --public-key]
+	//     Path to a PEM public key used to validate tokens
+	//
+	//   Retired JWT Secrets [JSON key: "retired-secrets", env RETIRED_SECRETS, --retired-secrets]
+	//     Comma separated list of previous secrets still accepted for validation
+	//
+	//   Retired JWT Keys [JSON key: "retired-keys", env RETIRED_KEYS, --retired-keys]
+	//     Comma separated list of previous public key paths still accepted for validation
+	//
+	//   JWT Algorithms [JSON key: "algorithms", env ALGORITHMS, --algorithms]
+	//     Comma separated list of accepted signing algorithms. Defaults to the key's algorithm
+	//
+	//   JWT Issuer [JSON key: "issuer", env ISSUER, --issuer]
+	//     Issuer set on, and required for, tokens
+	//
+	//   JWT Audience [JSON key: "audience", env AUDIENCE, --audience]
+	//     Comma separated list of audiences set on, and required for, tokens
+	//
+	//   JWT Leeway [JSON key: "leeway", env LEEWAY, --leeway]
+	//     Leeway allowed for clock skew when checking token times (default: 30s)
+	//
+	//   Shared Tokens [--shared-tokens]
+	//     Allow session cookies and bearer tokens to be used interchangeably (default: false)
+	//
+	//   Encrypt Sessions [--encrypt-sessions]
+	//     Encrypt session cookies so their claims cannot be read (default: false)
+	//
 	//   Cookie Domain [JSON key: "domain", env DOMAIN, --domain]
 	//     Cookie domain, leave blank to allow insecure cookies
 	//
 	//   Session TTL [JSON key: "session-ttl", env SESSION_TTL, --session-ttl]
 	//     TTL for sessions (default: 12h0m0s)
 	//
+	//   Session Refresh [JSON key: "session-refresh", env SESSION_REFRESH, --session-refresh]
+	//     Fraction of the session TTL after which a session is renewed, 0 to disable (default: 0)
+	//
+	//   Refresh TTL [JSON key: "refresh-ttl", env REFRESH_TTL, --refresh-ttl]
+	//     TTL for refresh tokens (default: 720h0m0s)
+	//
 	//   Login Timebox [JSON key: "login-timebox", env LOGIN_TIMEBOX, --login-timebox]
 	//     Minimum time it will take for a login attempt to return (default: 500ms)
+	//
+	//   Cookie Name [JSON key: "cookie-name", env COOKIE_NAME, --cookie-name]
+	//     Name of the session cookie, leave blank to use the default
+	//
+	//   Cookie Path [JSON key: "cookie-path", env COOKIE_PATH, --cookie-path]
+	//     Path the session cookie is valid for (default: /)
+	//
+	//   Cookie SameSite [JSON key: "cookie-same-site", env COOKIE_SAME_SITE, --cookie-same-site]
+	//     SameSite mode for cookies: lax, strict, or none (default: lax)
+	//
+	//   Cookie Prefix [JSON key: "cookie-prefix", env COOKIE_PREFIX, --cookie-prefix]
+	//     Cookie name prefix: host for __Host-, secure for __Secure-, or blank for none
+	//
+	//   Secure Cookie [--cookie-secure]
+	//     Always set the Secure attribute on cookies (default: false)
 }
 
 func ExampleSecurity_Secure() {
@@ -71,3 +141,17 @@ func ExampleSecurity_Secure() {
 	// false
 	// true
 }
+
+func ExampleSecurity_SecureCookies() {
+	s := config.Security{}
+
+	fmt.Println(s.SecureCookies())
+
+	s.Cookie.Secure = true
+
+	fmt.Println(s.SecureCookies())
+
+	// Output:
+	// false
+	// true
+}
@@ -1,6 +1,10 @@
 package config
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/domdavis/gofigure"
@@ -24,6 +28,16 @@ type Server struct {
 	// Key path for TLS.
 	Key string
 
+	// ClientCA path to a PEM encoded bundle of the certificate authorities
+	// used to verify client certificates. Leave blank if client certificates
+	// are not required.
+	ClientCA string
+
+	// ClientAuth mode for client certificates, one of none, request, require,
+	// verify, or require-verify. Leave blank to use require-verify if a
+	// ClientCA is set, and none otherwise.
+	ClientAuth string
+
 	// Development flag, can be passed to Gin to control how it is set up.
 	Development bool
 
@@ -34,6 +48,19 @@ type Server struct {
 	ReadTimeout time.Duration
 }
 
+// Client certificate authentication modes.
+const (
+	ClientAuthNone          = "none"
+	ClientAuthRequest       = "request"
+	ClientAuthRequire       = "require"
+	ClientAuthVerify        = "verify"
+	ClientAuthRequireVerify = "require-verify"
+)
+
+// ErrInvalidClientAuth is returned if the client certificate settings are
+// invalid.
+var ErrInvalidClientAuth = errors.New("invalid client certificate settings")
+
 const (
 	defaultPort    = 8000
 	defaultTimeout = 60 * time.Second
@@ -57,6 +84,12 @@ func (s *Server) Register(c *gofigure.Configuration) {
 	group.Add(gofigure.Optional("TLS Kep Path", "server-key-path",
 		&s.Key, "", gofigure.NamedSources, gofigure.MaskUnset,
 		"Path to TLS key file"))
+	group.Add(gofigure.Optional("TLS Client CA Path", "server-client-ca",
+		&s.ClientCA, "", gofigure.NamedSources, gofigure.MaskUnset,
+		"Path to the CA certificates used to verify client certificates"))
+	group.Add(gofigure.Optional("TLS Client Auth", "server-client-auth",
+		&s.ClientAuth, "", gofigure.NamedSources, gofigure.HideUnset,
+		"Client certificate mode: none, request, require, verify, or require-verify"))
 	group.Add(gofigure.Optional("Dev Mode", "dev", &s.Development, false,
 		gofigure.Flag, gofigure.ReportValue, "Set development mode"))
 	group.Add(gofigure.Optional("Write Timeout", "server-write-timeout",
@@ -71,3 +104,38 @@ func (s *Server) Register(c *gofigure.Configuration) {
 func (s *Server) TLS() bool {
 	return s.Certificate != "" && s.Key != ""
 }
+
+// ClientAuthType returns the tls.ClientAuthType for the ClientAuth mode. An
+// error is returned if the mode is unknown, or if the mode verifies client
+// certificates but no ClientCA is set, since the system roots would be used
+// to verify them.
+func (s *Server) ClientAuthType() (tls.ClientAuthType, error) {
+	var mode tls.ClientAuthType
+
+	switch strings.ToLower(s.ClientAuth) {
+	case "":
+		if s.ClientCA == "" {
+			return tls.NoClientCert, nil
+		}
+
+		mode = tls.RequireAndVerifyClientCert
+	case ClientAuthNone:
+		mode = tls.NoClientCert
+	case ClientAuthRequest:
+		mode = tls.RequestClientCert
+	case ClientAuthRequire:
+		mode = tls.RequireAnyClientCert
+	case ClientAuthVerify:
+		mode = tls.VerifyClientCertIfGiven
+	case ClientAuthRequireVerify:
+		mode = tls.RequireAndVerifyClientCert
+	default:
+		return tls.NoClientCert, fmt.Errorf("%w: unknown mode %q", ErrInvalidClientAuth, s.ClientAuth)
+	}
+
+	if mode >= tls.VerifyClientCertIfGiven && s.ClientCA == "" {
+		return tls.NoClientCert, fmt.Errorf("%w: %s requires a client CA", ErrInvalidClientAuth, s.ClientAuth)
+	}
+
+	return mode, nil
+}
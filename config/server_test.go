@@ -1,10 +1,13 @@
 package config_test
 
 import (
+	"crypto/tls"
 	"fmt"
+	"testing"
 
 	"github.com/domdavis/gofigure"
 	"github.com/domdavis/tonic/config"
+	"github.com/stretchr/testify/assert"
 )
 
 func ExampleServer_Register() {
@@ -35,6 +38,7 @@ func ExampleServer_Register() {
 	//   Server Port: 8000
 	//   TLS Certificate Path: UNSET
 	//   TLS Kep Path: UNSET
+	//   TLS Client CA Path: UNSET
 	//   Dev Mode: false
 	//   Write Timeout: 1m0s
 	//   Read Timeout: 1m0s
@@ -55,6 +59,12 @@ func ExampleServer_Register() {
 	//   TLS Kep Path [JSON key: "server-key-path", env SERVER_KEY_PATH, --server-key-path]
 	//     Path to TLS key file
 	//
+	//   TLS Client CA Path [JSON key: "server-client-ca", env SERVER_CLIENT_CA, --server-client-ca]
+	//     Path to the CA certificates used to verify client certificates
+	//
+	//   TLS Client Auth [JSON key: "server-client-auth", env SERVER_CLIENT_AUTH, --server-client-auth]
+	//     Client certificate mode: none, request, require, verify, or require-verify
+	//
 	//   Dev Mode [--dev]
 	//     Set development mode (default: false)
 	//
@@ -79,3 +89,74 @@ func ExampleServer_TLS() {
 	// false
 	// true
 }
+
+func ExampleServer_ClientAuthType() {
+	s := config.Server{ClientCA: "ca.crt"}
+
+	mode, err := s.ClientAuthType()
+
+	fmt.Println(mode == tls.RequireAndVerifyClientCert, err)
+
+	// Output:
+	// true <nil>
+}
+
+func TestServer_ClientAuthType(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		server config.Server
+		mode   tls.ClientAuthType
+		err    error
+	}{
+		"no client CA": {
+			server: config.Server{},
+			mode:   tls.NoClientCert,
+		},
+		"none": {
+			server: config.Server{ClientCA: "ca.crt", ClientAuth: "none"},
+			mode:   tls.NoClientCert,
+		},
+		"request": {
+			server: config.Server{ClientAuth: "request"},
+			mode:   tls.RequestClientCert,
+		},
+		"require": {
+			server: config.Server{ClientAuth: "Require"},
+			mode:   tls.RequireAnyClientCert,
+		},
+		"verify": {
+			server: config.Server{ClientCA: "ca.crt", ClientAuth: "verify"},
+			mode:   tls.VerifyClientCertIfGiven,
+		},
+		"verify without CA": {
+			server: config.Server{ClientAuth: "verify"},
+			err:    config.ErrInvalidClientAuth,
+		},
+		"unknown": {
+			server: config.Server{ClientCA: "ca.crt", ClientAuth: "always"},
+			err:    config.ErrInvalidClientAuth,
+		},
+		"require-verify": {
+			server: config.Server{ClientCA: "ca.crt", ClientAuth: "require-verify"},
+			mode:   tls.RequireAndVerifyClientCert,
+		},
+		"require-verify CA": {
+			server: config.Server{ClientAuth: "require-verify"},
+			err:    config.ErrInvalidClientAuth,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			mode, err := tc.server.ClientAuthType()
+
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.mode, mode)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package cookie
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -8,65 +9,217 @@ import (
 	"github.com/domdavis/tonic"
 	"github.com/domdavis/tonic/config"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 )
 
-// Name of the dropped cookie.
+// Name of the dropped cookie. The name can be changed, and prefixed, using the
+// Cookie settings in config.Security.
 const Name = "GinAndTonicAuth"
 
 // Drop a cookie with an authentication token. The token will contain the
-// authorisation claims taken from the context, and a TLL. The cookie will also
-// be set with a TTL, but this is not used for authentication since it can be
-// tampered with. It is simply used as a mechanism to allow the browser to tidy
-// up expired cookies.
+// authorisation claims taken from the context, a new session ID, and a TLL. The
+// cookie will also be set with a TTL, but this is not used for authentication
+// since it can be tampered with. It is simply used as a mechanism to allow the
+// browser to tidy up expired cookies.
 //
 // The dropped cookie can be used with the Authenticate middleware handler.
 func Drop(c *gin.Context, security config.Security, claims ...string) error {
-	signatory := &tonic.Signatory{TTL: security.SessionTTL, Secret: security.Secret}
-	signatory.Initialise()
-
 	maxAge := int(security.SessionTTL.Round(time.Second).Seconds())
 
 	if security.SessionTTL <= 0 || maxAge <= 0 {
 		return fmt.Errorf("%w: %v", tonic.ErrInvalidTTL, security.SessionTTL)
 	}
 
-	token, err := signatory.Sign(c, claims...)
+	signatory, err := sessions(security)
+
+	if err != nil {
+		return fmt.Errorf("failed to drop authorisation cookie: %w", err)
+	}
+
+	signatory.Revocations = tonic.Revocations(c)
+
+	payload := jwt.MapClaims{SessionIDClaim: tonic.GenerateID()}
+
+	for _, claim := range claims {
+		payload[claim], _ = c.Get(claim)
+	}
+
+	token, err := signatory.Issue(payload)
 
 	if err != nil {
 		return fmt.Errorf("failed to drop authorisation cookie: %w", err)
 	}
 
-	c.SetCookie(Name, token, maxAge, "/", security.Domain, security.Secure(), true)
+	http.SetCookie(c.Writer, bake(security, security.Cookie.Named(Name), token, maxAge))
 
 	return nil
 }
 
+// renew the session cookie if it's older than the refresh fraction of the
+// SessionTTL given in the security settings. The claims held in the context
+// must be from the current session token.
+func renew(c *gin.Context, security config.Security, signatory *tonic.Signatory, token string) {
+	if security.SessionRefresh <= 0 {
+		return
+	}
+
+	issued := time.Unix(int64(tonic.Get[float64](c, tonic.IssuedAtClaim)), 0)
+	threshold := time.Duration(float64(security.SessionTTL) * security.SessionRefresh)
+
+	if time.Since(issued) < threshold {
+		return
+	}
+
+	renewed, err := signatory.Renew(token)
+
+	if err != nil {
+		//nolint:errcheck // Gin is handling this for us.
+		_ = c.Error(fmt.Errorf("failed to renew session: %w", err))
+
+		return
+	}
+
+	maxAge := int(security.SessionTTL.Round(time.Second).Seconds())
+
+	http.SetCookie(c.Writer, bake(security, security.Cookie.Named(Name), renewed, maxAge))
+}
+
 // Clear the authentication cookie.
 func Clear(c *gin.Context, security config.Security) {
-	c.SetCookie(Name, "", -1, "/", security.Domain, security.Secure(), true)
+	http.SetCookie(c.Writer, bake(security, security.Cookie.Named(Name), "", -1))
+}
+
+// Logout revokes the session token held in the authentication cookie, then
+// clears the cookie. Unlike Clear, the revoked token cannot be used again even
+// if the browser keeps hold of it. Logout requires a RevocationStore to have
+// been set on the context using tonic.Revocable. The cookie is cleared even if
+// the token cannot be revoked.
+func Logout(c *gin.Context, security config.Security) error {
+	defer Clear(c, security)
+
+	token, err := c.Cookie(security.Cookie.Named(Name))
+
+	if err != nil {
+		return nil
+	}
+
+	signatory, err := sessions(security)
+
+	if err == nil {
+		signatory.Revocations = tonic.Revocations(c)
+		err = signatory.Revoke(token)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// Authenticator authenticates requests using the session token held in the
+// authentication cookie. An Authenticator can be used with an auth.Chain, or
+// using the Authenticate middleware.
+type Authenticator struct {
+	security  config.Security
+	signatory *tonic.Signatory
+	err       error
+}
+
+// Method is the authentication method used by an Authenticator.
+const Method = "cookie"
+
+// NewAuthenticator returns an Authenticator using the given security settings.
+// Bearer tokens will not authenticate unless shared tokens are enabled in the
+// security settings.
+func NewAuthenticator(security config.Security) *Authenticator {
+	signatory, err := sessions(security)
+
+	if err != nil {
+		err = fmt.Errorf("%w: %w", tonic.ErrMisconfigured, err)
+	}
+
+	return &Authenticator{security: security, signatory: signatory, err: err}
+}
+
+// Method returns the authentication method used by the Authenticator.
+func (a *Authenticator) Method() string {
+	return Method
+}
+
+// Authenticate the request, setting the claims held in the session token on
+// the context. tonic.ErrMissingToken is returned if the request doesn't have an
+// authentication cookie, and tonic.ErrMisconfigured if the security settings
+// are invalid. If session refresh is configured then the cookie will
+// be re-issued once the session is old enough. Revoked sessions will not
+// authenticate if a RevocationStore has been set on the context using
+// tonic.Revocable.
+func (a *Authenticator) Authenticate(c *gin.Context) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	revocable := *a.signatory
+	revocable.Revocations = tonic.Revocations(c)
+	token, err := c.Cookie(a.security.Cookie.Named(Name))
+
+	if err != nil {
+		err = tonic.ErrMissingToken
+	} else {
+		err = revocable.Validate(c, token)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to authenticate cookie: %w", err)
+	}
+
+	renew(c, a.security, &revocable, token)
+
+	return nil
 }
 
 // Authenticate a request by checking for a JWT token in a cookie. Authenticate
 // uses sensible defaults if no configuration is set, and will use a generated
 // secret if none is set. Failure to authenticate will abort the middleware
 // chain and either redirect the request to the given URL, or return
-// http.StatusUnauthorized if the redirect is blank.
+// http.StatusUnauthorized if the redirect is blank. If the configured keys
+// cannot be loaded then every request will fail with
+// http.StatusInternalServerError. Bearer tokens will not authenticate unless
+// shared tokens are enabled in the security settings. If session refresh is
+// configured then the cookie will be re-issued once the session is old enough.
+// Revoked sessions will not authenticate if a RevocationStore has been set on
+// the context using tonic.Revocable. The reason a cookie failed to authenticate
+// is passed to c.Error. Redirected GET requests carry the requested page in the
+// next query parameter, which the login handler can send the user back to
+// using Return.
 func Authenticate(security config.Security, redirect string) gin.HandlerFunc {
-	signatory := &tonic.Signatory{Secret: security.Secret}
-	signatory.Initialise()
+	a := NewAuthenticator(security)
 
 	return func(c *gin.Context) {
-		var authorised bool
+		if a.err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(fmt.Errorf("failed to authenticate cookie: %w", a.err))
+			c.Abort()
+			c.String(http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError))
+
+			return
+		}
 
-		if token, err := c.Cookie(Name); err == nil {
-			authorised = signatory.Validate(c, token)
+		invalid := a.Authenticate(c)
+
+		// Redirecting visitors without a session is part of the normal login
+		// flow, so it's not reported as an error.
+		if invalid != nil && !(redirect != "" && errors.Is(invalid, tonic.ErrMissingToken)) {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(invalid)
 		}
 
 		switch {
-		case !authorised && redirect != "":
+		case invalid != nil && redirect != "":
 			c.Abort()
-			c.Redirect(http.StatusTemporaryRedirect, redirect)
-		case !authorised && redirect == "":
+			c.Redirect(http.StatusTemporaryRedirect, ReturnTo(c, redirect))
+		case invalid != nil && redirect == "":
 			c.Abort()
 			c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
 		default:
@@ -74,3 +227,41 @@ func Authenticate(security config.Security, redirect string) gin.HandlerFunc {
 		}
 	}
 }
+
+// bake an HTTP only cookie holding the given value, using the Cookie settings.
+func bake(security config.Security, name, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     security.Cookie.Scope(),
+		Domain:   security.Domain,
+		Secure:   security.SecureCookies(),
+		HttpOnly: true,
+		SameSite: security.Cookie.Mode(),
+	}
+}
+
+// sessions returns a Signatory for session tokens, sharing its keys with every
+// other Signatory for the security settings. An error is returned if the Cookie
+// settings are invalid.
+func sessions(security config.Security) (*tonic.Signatory, error) {
+	if err := security.Cookie.Validate(security.Domain, security.SecureCookies()); err != nil {
+		return nil, err
+	}
+
+	signatory, err := tonic.SharedSignatory(security)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid security settings: %w", err)
+	}
+
+	signatory.Type = tonic.SessionType
+	signatory.Encrypt = security.EncryptSessions
+
+	if security.SharedTokens {
+		signatory.AcceptTypes = []string{tonic.AccessType}
+	}
+
+	return signatory, nil
+}
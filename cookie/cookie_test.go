@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/domdavis/tonic/middleware"
 	"github.com/domdavis/tonic/register"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -103,6 +107,17 @@ func TestDrop(t *testing.T) {
 
 		assert.ErrorContains(t, err, "failed to drop")
 	})
+
+	t.Run("An invalid key will error", func(t *testing.T) {
+		t.Parallel()
+
+		c := &gin.Context{}
+		s := config.Security{SessionTTL: time.Hour, PrivateKey: "missing.key"}
+
+		err := cookie.Drop(c, s)
+
+		assert.ErrorContains(t, err, "failed to drop")
+	})
 }
 
 func TestAuthenticate(t *testing.T) {
@@ -122,7 +137,9 @@ func TestAuthenticate(t *testing.T) {
 
 		register.Ping(router)
 
-		signatory := &tonic.Signatory{TTL: security.SessionTTL, Secret: security.Secret}
+		signatory := &tonic.Signatory{
+			TTL: security.SessionTTL, Secret: security.Secret, Type: tonic.SessionType,
+		}
 		signatory.Initialise()
 
 		w := httptest.NewRecorder()
@@ -201,6 +218,89 @@ func TestAuthenticate(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
 	})
 
+	t.Run("Cookies signed with a private key validate with the public key", func(t *testing.T) {
+		t.Parallel()
+
+		router := gin.New()
+		router.Use(cookie.Authenticate(config.Security{PublicKey: "../testdata/ecdsa.pub"}, ""))
+
+		register.Ping(router)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		err := cookie.Drop(c, config.Security{
+			SessionTTL: time.Hour, PrivateKey: "../testdata/ecdsa.key",
+		})
+
+		assert.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+
+		assert.NoError(t, err)
+
+		req.AddCookie(w.Result().Cookies()[0])
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("Bearer tokens are rejected unless tokens are shared", func(t *testing.T) {
+		t.Parallel()
+
+		shared := security
+		shared.SharedTokens = true
+
+		signatory := &tonic.Signatory{
+			TTL: security.SessionTTL, Secret: security.Secret, Type: tonic.AccessType,
+		}
+
+		token, err := signatory.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+
+		for s, code := range map[config.Security]int{
+			security: http.StatusUnauthorized,
+			shared:   http.StatusOK,
+		} {
+			router := gin.New()
+			router.Use(cookie.Authenticate(s, ""))
+
+			register.Ping(router)
+
+			req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+
+			assert.NoError(t, err)
+
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: token})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, code, w.Code)
+		}
+	})
+
+	t.Run("An invalid key will fail", func(t *testing.T) {
+		t.Parallel()
+
+		router := gin.New()
+		router.Use(cookie.Authenticate(config.Security{PublicKey: "missing.pub"}, ""))
+
+		register.Ping(router)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+
+		assert.NoError(t, err)
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+
 	t.Run("Redirects will occur on auth failure", func(t *testing.T) {
 		t.Parallel()
 
@@ -221,5 +321,380 @@ func TestAuthenticate(t *testing.T) {
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusTemporaryRedirect, w.Result().StatusCode)
+		assert.Equal(t, "/redirect?next=%2Fping", w.Result().Header.Get("Location"))
+	})
+}
+
+func TestAuthenticate_refresh(t *testing.T) {
+	const endpoint = "/ping"
+
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour, SessionRefresh: 0.5}
+
+	request := func(t *testing.T, security config.Security, issued time.Time) *http.Response {
+		t.Helper()
+
+		router := gin.New()
+		router.Use(cookie.Authenticate(security, ""))
+
+		register.Ping(router)
+
+		token := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
+			tonic.ExpiryClaim:   issued.Add(security.SessionTTL).Unix(),
+			tonic.IssuedAtClaim: issued.Unix(),
+			"user":              "user",
+		})
+		token.Header["typ"] = tonic.SessionType
+
+		value, err := token.SignedString([]byte(security.Secret))
+
+		assert.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+
+		assert.NoError(t, err)
+
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: value})
+		router.ServeHTTP(w, req)
+
+		return w.Result()
+	}
+
+	t.Run("Old sessions are renewed", func(t *testing.T) {
+		t.Parallel()
+
+		res := request(t, security, time.Now().Add(-time.Minute*45))
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Len(t, res.Cookies(), 1)
+
+		signatory := &tonic.Signatory{
+			TTL: security.SessionTTL, Secret: security.Secret, Type: tonic.SessionType,
+		}
+		ctx := &gin.Context{}
+
+		assert.NoError(t, signatory.Validate(ctx, res.Cookies()[0].Value))
+		assert.Equal(t, "user", tonic.Get[string](ctx, "user"))
+		assert.InDelta(t, float64(time.Now().Unix()),
+			tonic.Get[float64](ctx, tonic.IssuedAtClaim), 5)
+	})
+
+	t.Run("New sessions are not renewed", func(t *testing.T) {
+		t.Parallel()
+
+		res := request(t, security, time.Now().Add(-time.Minute*15))
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Empty(t, res.Cookies())
+	})
+
+	t.Run("Sessions are not renewed if refresh is disabled", func(t *testing.T) {
+		t.Parallel()
+
+		disabled := security
+		disabled.SessionRefresh = 0
+
+		res := request(t, disabled, time.Now().Add(-time.Minute*45))
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Empty(t, res.Cookies())
+	})
+}
+
+func TestLogout(t *testing.T) {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+	t.Run("Logged out sessions will not authenticate", func(t *testing.T) {
+		t.Parallel()
+
+		router := gin.New()
+		router.Use(tonic.Revocable(tonic.NewMemoryRevocations()))
+		router.GET("/login", func(c *gin.Context) {
+			_ = cookie.Drop(c, security)
+		})
+		router.GET("/logout", func(c *gin.Context) {
+			assert.NoError(t, cookie.Logout(c, security))
+		})
+		router.GET("/ping", cookie.Authenticate(security, ""))
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/login", nil)
+
+		assert.NoError(t, err)
+
+		router.ServeHTTP(w, req)
+		session := w.Result().Cookies()[0]
+
+		for _, path := range []string{"/ping", "/logout"} {
+			w = httptest.NewRecorder()
+			req, err = http.NewRequest(http.MethodGet, path, nil)
+
+			assert.NoError(t, err)
+
+			req.AddCookie(session)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+
+		assert.Equal(t, -1, w.Result().Cookies()[0].MaxAge)
+
+		w = httptest.NewRecorder()
+		req, err = http.NewRequest(http.MethodGet, "/ping", nil)
+
+		assert.NoError(t, err)
+
+		req.AddCookie(session)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Logout without a store clears the cookie and errors", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+		c.Request.AddCookie(&http.Cookie{Name: cookie.Name, Value: "token"})
+
+		assert.Error(t, cookie.Logout(c, security))
+		assert.Equal(t, -1, w.Result().Cookies()[0].MaxAge)
+	})
+
+	t.Run("Logout without a cookie clears the cookie", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+
+		assert.NoError(t, cookie.Logout(c, security))
+		assert.Equal(t, -1, w.Result().Cookies()[0].MaxAge)
+	})
+}
+
+func TestAuthenticate_errors(t *testing.T) {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+	for name, tc := range map[string]struct {
+		cookie   string
+		redirect string
+		err      error
+	}{
+		"Invalid cookies are reported": {
+			cookie: "garbage",
+			err:    tonic.ErrMalformed,
+		},
+		"Missing cookies are reported": {
+			err: tonic.ErrMissingToken,
+		},
+		"Invalid cookies are reported when redirecting": {
+			cookie:   "garbage",
+			redirect: "/login",
+			err:      tonic.ErrMalformed,
+		},
+		"Missing cookies are not reported when redirecting": {
+			redirect: "/login",
+		},
+	} {
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var errs []*gin.Error
+
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Next()
+				errs = c.Errors
+			})
+			router.Use(cookie.Authenticate(security, tc.redirect))
+			register.Ping(router)
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, "/ping", nil)
+
+			assert.NoError(t, err)
+
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: cookie.Name, Value: tc.cookie})
+			}
+
+			router.ServeHTTP(w, req)
+
+			if tc.err == nil {
+				assert.Empty(t, errs)
+			} else {
+				assert.Len(t, errs, 1)
+				assert.ErrorIs(t, errs[0], tc.err)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_encrypted(t *testing.T) {
+	t.Run("Encrypted sessions are decrypted transparently", func(t *testing.T) {
+		t.Parallel()
+
+		security := config.Security{Secret: "secret", SessionTTL: time.Hour, EncryptSessions: true}
+
+		router := gin.New()
+		router.GET("/login", func(c *gin.Context) {
+			c.Set("user", "user")
+			assert.NoError(t, cookie.Drop(c, security, "user"))
+		})
+		router.GET("/ping", cookie.Authenticate(security, ""), func(c *gin.Context) {
+			c.String(http.StatusOK, c.GetString("user"))
+		})
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/login", nil)
+
+		assert.NoError(t, err)
+
+		router.ServeHTTP(w, req)
+		session := w.Result().Cookies()[0]
+
+		assert.Equal(t, 4, strings.Count(session.Value, "."))
+
+		w = httptest.NewRecorder()
+		req, err = http.NewRequest(http.MethodGet, "/ping", nil)
+
+		assert.NoError(t, err)
+
+		req.AddCookie(session)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user", w.Body.String())
+	})
+}
+
+func TestDrop_settings(t *testing.T) {
+	t.Run("Cookie settings are honoured", func(t *testing.T) {
+		t.Parallel()
+
+		security := config.Security{
+			Secret:     "secret",
+			SessionTTL: time.Hour,
+			Cookie: config.Cookie{
+				Name:     "App",
+				Path:     "/app",
+				SameSite: config.SameSiteStrict,
+				Prefix:   config.SecurePrefix,
+				Secure:   true,
+			},
+		}
+
+		router := gin.New()
+		router.GET("/login", func(c *gin.Context) {
+			assert.NoError(t, cookie.Drop(c, security))
+		})
+		router.GET("/app", cookie.Authenticate(security, ""), func(c *gin.Context) {
+			cookie.Clear(c, security)
+		})
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/login", nil)
+
+		router.ServeHTTP(w, req)
+
+		cookies := w.Result().Cookies()
+
+		assert.Len(t, cookies, 1)
+		assert.Equal(t, "__Secure-App", cookies[0].Name)
+		assert.Equal(t, "/app", cookies[0].Path)
+		assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+		assert.True(t, cookies[0].Secure)
+		assert.True(t, cookies[0].HttpOnly)
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/app", nil)
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookies[0].Value})
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/app", nil)
+		req.AddCookie(cookies[0])
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		cleared := w.Result().Cookies()
+
+		assert.Len(t, cleared, 1)
+		assert.Equal(t, "__Secure-App", cleared[0].Name)
+		assert.Equal(t, "/app", cleared[0].Path)
+		assert.Less(t, cleared[0].MaxAge, 0)
+	})
+
+	t.Run("Invalid cookie settings will fail", func(t *testing.T) {
+		t.Parallel()
+
+		security := config.Security{
+			Secret:     "secret",
+			SessionTTL: time.Hour,
+			Domain:     "example.com",
+			Cookie:     config.Cookie{Prefix: config.HostPrefix},
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		assert.ErrorIs(t, cookie.Drop(c, security), config.ErrInvalidCookie)
+
+		router := gin.New()
+		router.Use(cookie.Authenticate(security, ""))
+		register.Ping(router)
+
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestAuthenticate_secretFile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Cookies dropped after the secret changes will authenticate", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "secret")
+
+		assert.NoError(t, os.WriteFile(path, []byte("old secret"), 0o600))
+
+		security := config.Security{SecretFile: path, SessionTTL: time.Hour}
+		router := gin.New()
+		router.GET("/login", func(c *gin.Context) {
+			assert.NoError(t, cookie.Drop(c, security))
+		})
+		router.GET("/", cookie.Authenticate(security, ""), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		assert.NoError(t, os.WriteFile(path, []byte("new secret"), 0o600))
+		assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		for _, c := range w.Result().Cookies() {
+			req.AddCookie(c)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
 	})
 }
@@ -0,0 +1,258 @@
+package cookie
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/gin-gonic/gin"
+)
+
+// CSRF names used for the cookie, header, form field, and context key holding
+// the CSRF token.
+const (
+	CSRFName   = "GinAndTonicCSRF"
+	CSRFHeader = "X-CSRF-Token"
+	CSRFField  = "csrf_token"
+	CSRFKey    = "tonic.csrf"
+)
+
+// csrfPurpose is used to derive the keys that sign CSRF tokens.
+const csrfPurpose = "csrf"
+
+// SessionIDClaim holds the session ID on session tokens. The session ID stays
+// the same when the session is renewed, and is used to bind CSRF tokens to the
+// session.
+const SessionIDClaim = "sid"
+
+// CSRF errors.
+var (
+	ErrInvalidCSRFToken = errors.New("invalid CSRF token")
+	ErrInvalidOrigin    = errors.New("invalid origin")
+)
+
+// CSRFToken returns the CSRF token for the current session, setting the CSRF
+// cookie if needed. The token must be sent back with any unsafe request, either
+// in the X-CSRF-Token header or the csrf_token form field, for the request to
+// pass the Protect middleware. CSRFToken should be called after the
+// Authenticate middleware so the token is bound to the session. An empty
+// string is returned if the token cannot be generated.
+func CSRFToken(c *gin.Context, security config.Security) string {
+	if token := c.GetString(CSRFKey); token != "" {
+		return token
+	}
+
+	signatory, err := sessions(security)
+
+	var keys [][]byte
+
+	if err == nil {
+		keys, err = signatory.DeriveKeys(csrfPurpose)
+	}
+
+	if err != nil {
+		//nolint:errcheck // Gin is handling this for us.
+		_ = c.Error(fmt.Errorf("failed to generate CSRF token: %w", err))
+
+		return ""
+	}
+
+	token, err := c.Cookie(security.Cookie.Prefixed(CSRFName))
+
+	if err != nil || !validCSRF(keys, session(c), token) {
+		token = signCSRF(keys[0], session(c), tonic.GenerateID())
+
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     security.Cookie.Prefixed(CSRFName),
+			Value:    token,
+			Path:     security.Cookie.Scope(),
+			Domain:   security.Domain,
+			Secure:   security.SecureCookies(),
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+
+	c.Set(CSRFKey, token)
+
+	return token
+}
+
+// CSRFInput returns a hidden form input holding the CSRF token, for use in
+// templates loaded using config.Server.Templates. The input is named using
+// CSRFField, and would typically be passed to the template with the rest of
+// the data, for example:
+//
+//	c.HTML(http.StatusOK, "form.html", gin.H{"csrf": cookie.CSRFInput(c, s)})
+func CSRFInput(c *gin.Context, security config.Security) template.HTML {
+	//nolint:gosec // The token is URL safe base64 and cannot contain markup.
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		CSRFField, CSRFToken(c, security)))
+}
+
+// Protect returns middleware that protects unsafe requests from cross site
+// request forgery. Requests using methods other than GET, HEAD, OPTIONS, and
+// TRACE must send the token from CSRFToken in the X-CSRF-Token header or the
+// csrf_token form field, matching the token in the CSRF cookie and bound to the
+// current session. If the Origin, or failing that the Referer, header is set
+// then it must also match the request host, the security Domain, or one of the
+// given origins. Origins on the security Domain must use the port of the
+// request, and https if cookies are secure. Protect should be used after the
+// Authenticate middleware.
+// Failures will abort the middleware chain and return http.StatusForbidden.
+func Protect(security config.Security, origins ...string) gin.HandlerFunc {
+	signatory, invalid := sessions(security)
+
+	if invalid == nil {
+		_, invalid = signatory.DeriveKeys(csrfPurpose)
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			c.Next()
+
+			return
+		}
+
+		err := invalid
+
+		if err == nil {
+			err = checkOrigin(c, security, origins)
+		}
+
+		if err == nil {
+			err = checkCSRF(c, security, signatory)
+		}
+
+		if err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(fmt.Errorf("failed CSRF check: %w", err))
+			c.Abort()
+			c.String(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// checkOrigin ensures the Origin or Referer header, if set, is acceptable.
+func checkOrigin(c *gin.Context, security config.Security, origins []string) error {
+	origin := c.GetHeader("Origin")
+
+	if origin == "" || origin == "null" {
+		origin = c.Request.Referer()
+	}
+
+	if origin == "" {
+		return nil
+	}
+
+	u, err := url.Parse(origin)
+
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidOrigin, origin)
+	}
+
+	if strings.EqualFold(u.Host, c.Request.Host) || onDomain(c, security, u) {
+		return nil
+	}
+
+	for _, allowed := range origins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), u.Scheme+"://"+u.Host) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q", ErrInvalidOrigin, origin)
+}
+
+// onDomain returns true if the origin is the security Domain, on the same port
+// as the request, using https if cookies are secure.
+func onDomain(c *gin.Context, security config.Security, origin *url.URL) bool {
+	switch {
+	case security.Domain == "", !strings.EqualFold(origin.Hostname(), security.Domain):
+		return false
+	case security.SecureCookies() && origin.Scheme != "https":
+		return false
+	default:
+		return origin.Port() == (&url.URL{Host: c.Request.Host}).Port()
+	}
+}
+
+// checkCSRF ensures the submitted token matches the cookie and session, and was
+// signed with a key derived from the Signatory's secrets.
+func checkCSRF(c *gin.Context, security config.Security, signatory *tonic.Signatory) error {
+	cookie, err := c.Cookie(security.Cookie.Prefixed(CSRFName))
+
+	if err != nil {
+		return fmt.Errorf("%w: missing cookie", ErrInvalidCSRFToken)
+	}
+
+	submitted := c.GetHeader(CSRFHeader)
+
+	if submitted == "" {
+		submitted = c.PostForm(CSRFField)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(submitted), []byte(cookie)) != 1 {
+		return fmt.Errorf("%w: token does not match cookie", ErrInvalidCSRFToken)
+	}
+
+	keys, err := signatory.DeriveKeys(csrfPurpose)
+
+	if err != nil {
+		return err
+	}
+
+	if !validCSRF(keys, session(c), cookie) {
+		return fmt.Errorf("%w: token is not for this session", ErrInvalidCSRFToken)
+	}
+
+	return nil
+}
+
+// session returns the ID of the current session, or an empty string if there
+// is no authenticated session.
+func session(c *gin.Context) string {
+	id, _ := c.Get(SessionIDClaim)
+	s, _ := id.(string)
+
+	return s
+}
+
+// signCSRF returns a CSRF token for the given nonce, bound to the session.
+func signCSRF(key []byte, session, nonce string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(nonce + "." + session))
+
+	return nonce + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validCSRF returns true if the CSRF token is bound to the session and was
+// signed with one of the keys.
+func validCSRF(keys [][]byte, session, token string) bool {
+	nonce, _, ok := strings.Cut(token, ".")
+
+	if !ok {
+		return false
+	}
+
+	for _, key := range keys {
+		if hmac.Equal([]byte(signCSRF(key, session, nonce)), []byte(token)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,267 @@
+package cookie_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type Browser struct {
+	router  *gin.Engine
+	cookies map[string]*http.Cookie
+}
+
+func NewBrowser(t *testing.T, security config.Security, origins ...string) *Browser {
+	t.Helper()
+
+	router := gin.New()
+	router.GET("/login", func(c *gin.Context) {
+		assert.NoError(t, cookie.Drop(c, security))
+	})
+
+	authorised := router.Group("/", cookie.Authenticate(security, ""),
+		cookie.Protect(security, origins...))
+	authorised.GET("/form", func(c *gin.Context) {
+		c.String(http.StatusOK, cookie.CSRFToken(c, security))
+	})
+	authorised.POST("/submit", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	return &Browser{router: router, cookies: map[string]*http.Cookie{}}
+}
+
+func (b *Browser) Do(t *testing.T, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+
+	for _, c := range b.cookies {
+		req.AddCookie(c)
+	}
+
+	b.router.ServeHTTP(w, req)
+
+	for _, c := range w.Result().Cookies() {
+		b.cookies[c.Name] = c
+	}
+
+	return w
+}
+
+func (b *Browser) Get(t *testing.T, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+
+	assert.NoError(t, err)
+
+	return b.Do(t, req)
+}
+
+func (b *Browser) Post(t *testing.T, token string, headers ...string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, "http://example.com/submit", nil)
+
+	assert.NoError(t, err)
+
+	req.Header.Set(cookie.CSRFHeader, token)
+
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	return b.Do(t, req).Code
+}
+
+func ExampleCSRFInput() {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/form", nil)
+
+	input := cookie.CSRFInput(c, security)
+
+	fmt.Println(strings.HasPrefix(string(input), `<input type="hidden" name="csrf_token"`))
+	fmt.Println(w.Result().Cookies()[0].Name)
+
+	// Output:
+	// true
+	// GinAndTonicCSRF
+}
+
+func TestProtect(t *testing.T) {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+	t.Run("Unsafe requests need a valid token", func(t *testing.T) {
+		t.Parallel()
+
+		b := NewBrowser(t, security)
+		b.Get(t, "/login")
+		token := b.Get(t, "/form").Body.String()
+
+		assert.Equal(t, token, b.Get(t, "/form").Body.String())
+		assert.Equal(t, http.StatusForbidden, b.Post(t, ""))
+		assert.Equal(t, http.StatusForbidden, b.Post(t, token+"x"))
+		assert.Equal(t, http.StatusNoContent, b.Post(t, token))
+	})
+
+	t.Run("Tokens can be sent as a form field", func(t *testing.T) {
+		t.Parallel()
+
+		b := NewBrowser(t, security)
+		b.Get(t, "/login")
+		token := b.Get(t, "/form").Body.String()
+
+		req, err := http.NewRequest(http.MethodPost, "/submit",
+			strings.NewReader(url.Values{cookie.CSRFField: {token}}.Encode()))
+
+		assert.NoError(t, err)
+
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		assert.Equal(t, http.StatusNoContent, b.Do(t, req).Code)
+	})
+
+	t.Run("Tokens are bound to the session", func(t *testing.T) {
+		t.Parallel()
+
+		b := NewBrowser(t, security)
+		b.Get(t, "/login")
+		token := b.Get(t, "/form").Body.String()
+
+		// Logging in again starts a new session.
+		b.Get(t, "/login")
+
+		assert.Equal(t, http.StatusForbidden, b.Post(t, token))
+		assert.NotEqual(t, token, b.Get(t, "/form").Body.String())
+	})
+
+	t.Run("Requests without a CSRF cookie are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		b := NewBrowser(t, security)
+		b.Get(t, "/login")
+
+		assert.Equal(t, http.StatusForbidden, b.Post(t, "token"))
+	})
+
+	t.Run("Cross origin requests are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		b := NewBrowser(t, security, "https://trusted.com/")
+		b.Get(t, "/login")
+		token := b.Get(t, "/form").Body.String()
+
+		assert.Equal(t, http.StatusNoContent, b.Post(t, token, "Origin", "http://example.com"))
+		assert.Equal(t, http.StatusNoContent, b.Post(t, token, "Origin", "https://trusted.com"))
+		assert.Equal(t, http.StatusNoContent, b.Post(t, token, "Referer", "http://example.com/form"))
+		assert.Equal(t, http.StatusForbidden, b.Post(t, token, "Origin", "https://evil.com"))
+		assert.Equal(t, http.StatusForbidden, b.Post(t, token, "Referer", "https://evil.com/form"))
+		assert.Equal(t, http.StatusForbidden, b.Post(t, token, "Origin", "garbage"))
+	})
+
+	t.Run("The security domain is a trusted origin", func(t *testing.T) {
+		t.Parallel()
+
+		s := security
+		s.Domain = "example.org"
+
+		b := NewBrowser(t, s)
+		b.Get(t, "/login")
+		token := b.Get(t, "/form").Body.String()
+
+		assert.Equal(t, http.StatusNoContent, b.Post(t, token, "Origin", "https://example.org"))
+		assert.Equal(t, http.StatusForbidden, b.Post(t, token, "Origin", "http://example.org"))
+		assert.Equal(t, http.StatusForbidden, b.Post(t, token, "Origin", "https://example.org:8443"))
+	})
+
+	t.Run("Secure cookies need the security domain to use https", func(t *testing.T) {
+		t.Parallel()
+
+		s := security
+		s.Domain = "localhost"
+
+		b := NewBrowser(t, s)
+		b.Get(t, "/login")
+		token := b.Get(t, "/form").Body.String()
+
+		assert.Equal(t, http.StatusNoContent, b.Post(t, token, "Origin", "http://localhost"))
+
+		s.Cookie.Secure = true
+
+		b = NewBrowser(t, s)
+		b.Get(t, "/login")
+		token = b.Get(t, "/form").Body.String()
+
+		assert.Equal(t, http.StatusNoContent, b.Post(t, token, "Origin", "https://localhost"))
+		assert.Equal(t, http.StatusForbidden, b.Post(t, token, "Origin", "http://localhost"))
+	})
+
+	t.Run("Cookie settings are honoured", func(t *testing.T) {
+		t.Parallel()
+
+		s := security
+		s.Cookie = config.Cookie{Prefix: config.HostPrefix, Secure: true}
+
+		b := NewBrowser(t, s)
+		b.Get(t, "/login")
+		token := b.Get(t, "/form").Body.String()
+
+		assert.Contains(t, b.cookies, "__Host-"+cookie.CSRFName)
+		assert.Equal(t, http.StatusNoContent, b.Post(t, token))
+	})
+
+	t.Run("Invalid settings will fail", func(t *testing.T) {
+		t.Parallel()
+
+		s := config.Security{PrivateKey: "testdata/missing.key"}
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/form", nil)
+
+		assert.Empty(t, cookie.CSRFToken(c, s))
+
+		router := gin.New()
+		router.POST("/submit", cookie.Protect(s))
+
+		req, err := http.NewRequest(http.MethodPost, "/submit", nil)
+
+		assert.NoError(t, err)
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Signing keys need an explicit secret", func(t *testing.T) {
+		t.Parallel()
+
+		s := config.Security{PrivateKey: "../testdata/ecdsa.key", SessionTTL: time.Hour}
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/form", nil)
+
+		assert.Empty(t, cookie.CSRFToken(c, s))
+		assert.ErrorIs(t, c.Errors.Last(), tonic.ErrMissingSecret)
+
+		s.Secret = "secret"
+		b := NewBrowser(t, s)
+		b.Get(t, "/login")
+
+		token := b.Get(t, "/form").Body.String()
+
+		assert.NotEmpty(t, token)
+		assert.Equal(t, http.StatusNoContent, b.Post(t, token))
+	})
+}
@@ -0,0 +1,145 @@
+package cookie
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// FlashLevel is the type of a flash message.
+type FlashLevel string
+
+// Flash message levels.
+const (
+	FlashInfo  FlashLevel = "info"
+	FlashWarn  FlashLevel = "warn"
+	FlashError FlashLevel = "error"
+)
+
+// FlashMessage is a message set by one request, and shown by the next.
+type FlashMessage struct {
+	Level FlashLevel `json:"level"`
+	Text  string     `json:"text"`
+}
+
+// Flash names used for the cookie, token type, and context key holding the
+// flash messages.
+const (
+	FlashName = "GinAndTonicFlash"
+	FlashType = "flash+jwt"
+	FlashKey  = "tonic.flash"
+)
+
+// FlashTTL is the length of time a flash message will wait to be read.
+const FlashTTL = time.Minute * 5
+
+const (
+	flashClaim   = "flashes"
+	pendingFlash = "tonic.flash.pending"
+)
+
+// Flash a message to be shown by the next request, typically after a redirect.
+// The messages are held in a signed cookie, which is read and cleared by the
+// LoadFlashes middleware. Multiple messages can be flashed by the same request.
+func Flash(c *gin.Context, security config.Security, level FlashLevel, text string) error {
+	signatory, err := flashes(security)
+
+	if err != nil {
+		return fmt.Errorf("failed to flash message: %w", err)
+	}
+
+	pending := append(tonic.Get[[]FlashMessage](c, pendingFlash), FlashMessage{Level: level, Text: text})
+	token, err := signatory.Issue(jwt.MapClaims{flashClaim: pending})
+
+	if err != nil {
+		return fmt.Errorf("failed to flash message: %w", err)
+	}
+
+	c.Set(pendingFlash, pending)
+	http.SetCookie(c.Writer, bake(security, security.Cookie.Prefixed(FlashName), token,
+		int(FlashTTL.Seconds())))
+
+	return nil
+}
+
+// LoadFlashes returns middleware that loads any flash messages set by the
+// previous request into the context, and clears the flash cookie so the
+// messages are only shown once. Use Flashes to get the messages, typically to
+// pass them to a template. Flash cookies that fail to validate are ignored,
+// with the reason passed to c.Error.
+func LoadFlashes(security config.Security) gin.HandlerFunc {
+	signatory, invalid := flashes(security)
+
+	return func(c *gin.Context) {
+		token, err := c.Cookie(security.Cookie.Prefixed(FlashName))
+
+		if err != nil {
+			c.Next()
+
+			return
+		}
+
+		http.SetCookie(c.Writer, bake(security, security.Cookie.Prefixed(FlashName), "", -1))
+
+		var messages []FlashMessage
+
+		if err = invalid; err == nil {
+			messages, err = parseFlashes(signatory, token)
+		}
+
+		if err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(fmt.Errorf("failed to load flash messages: %w", err))
+		} else {
+			c.Set(FlashKey, messages)
+		}
+
+		c.Next()
+	}
+}
+
+// Flashes returns the flash messages loaded by LoadFlashes, or nil if there are
+// none.
+func Flashes(c *gin.Context) []FlashMessage {
+	return tonic.Get[[]FlashMessage](c, FlashKey)
+}
+
+// parseFlashes returns the flash messages held in the token.
+func parseFlashes(signatory *tonic.Signatory, token string) ([]FlashMessage, error) {
+	var messages []FlashMessage
+
+	claims, err := signatory.Parse(token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, _ := json.Marshal(claims[flashClaim])
+
+	if err = json.Unmarshal(b, &messages); err != nil {
+		return nil, fmt.Errorf("%w: %s", tonic.ErrInvalidClaim, err.Error())
+	}
+
+	return messages, nil
+}
+
+// flashes returns a Signatory for flash messages.
+func flashes(security config.Security) (*tonic.Signatory, error) {
+	signatory, err := sessions(security)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signatory.Type = FlashType
+	signatory.TTL = FlashTTL
+	signatory.AcceptTypes = nil
+
+	return signatory, nil
+}
@@ -0,0 +1,145 @@
+package cookie_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleFlash() {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+	router := gin.New()
+	router.Use(cookie.LoadFlashes(security))
+	router.POST("/save", func(c *gin.Context) {
+		_ = cookie.Flash(c, security, cookie.FlashInfo, "Saved")
+		_ = cookie.Flash(c, security, cookie.FlashWarn, "Check your email")
+		c.Redirect(http.StatusSeeOther, "/")
+	})
+	router.GET("/", func(c *gin.Context) {
+		// Flashes would typically be passed to a template.
+		for _, flash := range cookie.Flashes(c) {
+			c.String(http.StatusOK, "%s: %s\n", flash.Level, flash.Text)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/save", nil))
+
+	cookies := w.Result().Cookies()
+	flash := cookies[len(cookies)-1]
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(flash)
+	router.ServeHTTP(w, req)
+
+	fmt.Print(w.Body.String())
+	fmt.Println(w.Result().Cookies()[0].MaxAge < 0)
+
+	// Output:
+	// info: Saved
+	// warn: Check your email
+	// true
+}
+
+func TestLoadFlashes(t *testing.T) {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+	load := func(t *testing.T, value string) ([]cookie.FlashMessage, []*gin.Error) {
+		t.Helper()
+
+		var (
+			messages []cookie.FlashMessage
+			errs     []*gin.Error
+		)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Next()
+			errs = c.Errors
+		})
+		router.Use(cookie.LoadFlashes(security))
+		router.GET("/", func(c *gin.Context) {
+			messages = cookie.Flashes(c)
+		})
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		if value != "" {
+			req.AddCookie(&http.Cookie{Name: cookie.FlashName, Value: value})
+		}
+
+		router.ServeHTTP(w, req)
+
+		return messages, errs
+	}
+
+	t.Run("Requests without flash messages have no flashes", func(t *testing.T) {
+		t.Parallel()
+
+		messages, errs := load(t, "")
+
+		assert.Nil(t, messages)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("Invalid flash cookies are reported", func(t *testing.T) {
+		t.Parallel()
+
+		messages, errs := load(t, "garbage")
+
+		assert.Nil(t, messages)
+		assert.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], tonic.ErrMalformed)
+	})
+
+	t.Run("Session cookies are not flash messages", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		assert.NoError(t, cookie.Drop(c, security))
+
+		messages, errs := load(t, w.Result().Cookies()[0].Value)
+
+		assert.Nil(t, messages)
+		assert.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], tonic.ErrWrongType)
+	})
+
+	t.Run("Invalid settings will fail", func(t *testing.T) {
+		t.Parallel()
+
+		s := config.Security{Secret: "secret", SessionTTL: time.Hour}
+		s.Cookie.Prefix = config.HostPrefix
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		assert.ErrorIs(t, cookie.Flash(c, s, cookie.FlashError, "Oops"), config.ErrInvalidCookie)
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Next()
+			assert.Len(t, c.Errors, 1)
+		})
+		router.Use(cookie.LoadFlashes(s))
+		router.GET("/", func(c *gin.Context) {})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "__Host-" + cookie.FlashName, Value: "token"})
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	})
+}
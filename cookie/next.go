@@ -0,0 +1,67 @@
+package cookie
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NextParam is the query parameter used to carry the page an unauthenticated
+// user asked for to the login page, and back again once they have logged in.
+const NextParam = "next"
+
+// ReturnTo returns the redirect URL with the page requested by the current
+// request added using the next query parameter. Only GET and HEAD requests are
+// returned to, so the redirect URL is returned unchanged for other requests, or
+// if the redirect URL cannot be parsed.
+func ReturnTo(c *gin.Context, redirect string) string {
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return redirect
+	}
+
+	u, err := url.Parse(redirect)
+
+	if err != nil {
+		return redirect
+	}
+
+	query := u.Query()
+	query.Set(NextParam, c.Request.URL.RequestURI())
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// Return redirects the request to the page given by the next query parameter
+// or form field, or to the fallback if next is missing or not Local. Return is
+// intended to be called by login handlers once the cookie has been dropped,
+// and responds with http.StatusSeeOther so the page is fetched with GET.
+func Return(c *gin.Context, fallback string) {
+	next := c.Query(NextParam)
+
+	if next == "" {
+		next = c.PostForm(NextParam)
+	}
+
+	if !Local(next) {
+		next = fallback
+	}
+
+	c.Redirect(http.StatusSeeOther, next)
+}
+
+// Local returns true if the target is a path on this host, and so is safe to
+// redirect to. Absolute URLs, protocol relative URLs such as //example.com, and
+// paths that browsers may treat as either of these, are not local.
+func Local(target string) bool {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") ||
+		strings.ContainsAny(target, "\\\t\r\n") {
+		return false
+	}
+
+	u, err := url.Parse(target)
+
+	return err == nil && u.Scheme == "" && u.Host == "" && u.User == nil
+}
@@ -0,0 +1,129 @@
+package cookie_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleReturn() {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+	router := gin.New()
+	router.GET("/account", cookie.Authenticate(security, "/login"))
+	router.POST("/login", func(c *gin.Context) {
+		// Check the user's credentials, then drop the cookie and send them
+		// back to the page they asked for.
+		_ = cookie.Drop(c, security)
+		cookie.Return(c, "/")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/account?tab=orders", nil))
+
+	login := w.Header().Get("Location")
+	u, _ := url.Parse(login)
+
+	fmt.Println(w.Code, login)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, login, nil))
+
+	fmt.Println(w.Code, u.Query().Get(cookie.NextParam), w.Header().Get("Location"))
+
+	// Output:
+	// 307 /login?next=%2Faccount%3Ftab%3Dorders
+	// 303 /account?tab=orders /account?tab=orders
+}
+
+func ExampleLocal() {
+	fmt.Println(cookie.Local("/account"))
+	fmt.Println(cookie.Local("https://example.com/account"))
+	fmt.Println(cookie.Local("//example.com/account"))
+
+	// Output:
+	// true
+	// false
+	// false
+}
+
+func TestReturnTo(t *testing.T) {
+	t.Run("Existing query parameters are kept", func(t *testing.T) {
+		t.Parallel()
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/page", nil)
+
+		assert.Equal(t, "/login?next=%2Fpage&realm=app", cookie.ReturnTo(c, "/login?realm=app"))
+	})
+
+	t.Run("Unsafe requests are not returned to", func(t *testing.T) {
+		t.Parallel()
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/page", nil)
+
+		assert.Equal(t, "/login", cookie.ReturnTo(c, "/login"))
+	})
+
+	t.Run("Invalid redirects are left alone", func(t *testing.T) {
+		t.Parallel()
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/page", nil)
+
+		assert.Equal(t, "%zz", cookie.ReturnTo(c, "%zz"))
+	})
+}
+
+func TestReturn(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		next     string
+		form     bool
+		location string
+	}{
+		"Local paths are returned to":             {next: "/account?tab=1", location: "/account?tab=1"},
+		"Form fields are used":                    {next: "/account", form: true, location: "/account"},
+		"Missing paths use the fallback":          {location: "/"},
+		"Absolute URLs use the fallback":          {next: "https://evil.com/", location: "/"},
+		"Protocol relative URLs use the fallback": {next: "//evil.com/", location: "/"},
+		"Backslashes use the fallback":            {next: "/\\evil.com/", location: "/"},
+		"Relative paths use the fallback":         {next: "account", location: "/"},
+		"Other schemes use the fallback":          {next: "javascript:alert(1)", location: "/"},
+		"Newlines use the fallback":               {next: "/\nevil", location: "/"},
+	} {
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			if tc.form {
+				c.Request = httptest.NewRequest(http.MethodPost, "/login",
+					strings.NewReader(url.Values{cookie.NextParam: {tc.next}}.Encode()))
+				c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				c.Request = httptest.NewRequest(http.MethodPost,
+					"/login?"+url.Values{cookie.NextParam: {tc.next}}.Encode(), nil)
+			}
+
+			cookie.Return(c, "/")
+
+			assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+			assert.Equal(t, tc.location, w.Header().Get("Location"))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package tonic
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes the data to the named file, creating it with the
+// given permissions if needed. The data is written to a temporary file in the
+// same directory, which then replaces the named file, so readers never see a
+// partially written file.
+func WriteFileAtomic(name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+
+	if err != nil {
+		//nolint:wrapcheck // Errors are returned unchanged, as with os.WriteFile.
+		return err
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Chmod(perm)
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+
+	//nolint:wrapcheck // Errors are returned unchanged, as with os.WriteFile.
+	return err
+}
@@ -0,0 +1,46 @@
+package tonic_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/domdavis/tonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Files are created and replaced", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "file")
+
+		assert.NoError(t, tonic.WriteFileAtomic(path, []byte("first"), 0o600))
+		assert.NoError(t, tonic.WriteFileAtomic(path, []byte("second"), 0o600))
+
+		b, err := os.ReadFile(path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "second", string(b))
+
+		info, err := os.Stat(path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		entries, err := os.ReadDir(filepath.Dir(path))
+
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("Missing directories will error", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "missing", "file")
+
+		assert.ErrorIs(t, tonic.WriteFileAtomic(path, []byte("data"), 0o600), os.ErrNotExist)
+	})
+}
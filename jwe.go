@@ -0,0 +1,211 @@
+package tonic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrDecryptionFailed is returned when an encrypted token cannot be decrypted.
+var ErrDecryptionFailed = errors.New("failed to decrypt token")
+
+// JWE header values used for encrypted tokens.
+const (
+	// DirectEncryption is the JWE alg used for encrypted tokens. The content
+	// encryption key is derived from the secret rather than being sent with
+	// the token.
+	DirectEncryption = "dir"
+
+	// A256GCM is the JWE enc used for encrypted tokens.
+	A256GCM = "A256GCM"
+)
+
+const (
+	jweParts     = 5
+	jweKeyLength = 32
+	jweInfo      = "tonic JWE A256GCM"
+)
+
+// jweHeader is the protected header of an encrypted token.
+//
+//nolint:tagliatelle // Header names are defined by RFC 7516.
+type jweHeader struct {
+	Algorithm   string `json:"alg"`
+	Encryption  string `json:"enc"`
+	ContentType string `json:"cty"`
+	KeyID       string `json:"kid,omitempty"`
+}
+
+// encrypted returns true if the token is a compact JWE rather than a JWT.
+func encrypted(tokenString string) bool {
+	return strings.Count(tokenString, ".") == jweParts-1
+}
+
+// encrypt a signed token as a compact JWE using direct encryption with
+// A256GCM. The content encryption key is derived from the secret of the key.
+func encrypt(key *Key, tokenString string) (string, error) {
+	aead, err := jweCipher(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	b, _ := json.Marshal(jweHeader{
+		Algorithm:   DirectEncryption,
+		Encryption:  A256GCM,
+		ContentType: "JWT",
+		KeyID:       key.ID,
+	})
+
+	header := base64.RawURLEncoding.EncodeToString(b)
+	iv := make([]byte, aead.NonceSize())
+
+	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
+		return "", fmt.Errorf("failed to encrypt token: %w", err)
+	}
+
+	sealed := aead.Seal(nil, iv, []byte(tokenString), []byte(header))
+	ciphertext, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+
+	return strings.Join([]string{
+		header,
+		"",
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// decrypt a compact JWE, returning the signed token it holds. The lookup
+// function is used to find the key for the kid in the JWE header.
+func decrypt(tokenString string, lookup func(id string) (*Key, error)) (string, error) {
+	var (
+		header jweHeader
+		parts  = strings.Split(tokenString, ".")
+		raw    = make([][]byte, jweParts)
+	)
+
+	for i, part := range parts {
+		b, err := base64.RawURLEncoding.DecodeString(part)
+
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrMalformed, err.Error())
+		}
+
+		raw[i] = b
+	}
+
+	if err := json.Unmarshal(raw[0], &header); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrMalformed, err.Error())
+	}
+
+	if header.Algorithm != DirectEncryption || header.Encryption != A256GCM || len(raw[1]) != 0 {
+		return "", fmt.Errorf("%w: unsupported JWE %s %s", ErrWrongAlgorithm,
+			header.Algorithm, header.Encryption)
+	}
+
+	key, err := lookup(header.KeyID)
+
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := jweCipher(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(raw[2]) != aead.NonceSize() {
+		return "", fmt.Errorf("%w: invalid IV", ErrMalformed)
+	}
+
+	plain, err := aead.Open(nil, raw[2], append(raw[3], raw[4]...), []byte(parts[0]))
+
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrDecryptionFailed, err.Error())
+	}
+
+	return string(plain), nil
+}
+
+// jweCipher returns the AES-GCM cipher for the given key. The key must hold a
+// secret, from which the content encryption key is derived using HKDF.
+func jweCipher(key *Key) (cipher.AEAD, error) {
+	secret, ok := key.Signing.([]byte)
+
+	if !ok {
+		return nil, fmt.Errorf("%w: encryption requires a secret", ErrUnsupportedKey)
+	}
+
+	cek := make([]byte, jweKeyLength)
+
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(jweInfo)), cek); err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+
+	// Neither of these can fail with a 32 byte key.
+	block, _ := aes.NewCipher(cek)
+	aead, _ := cipher.NewGCM(block)
+
+	return aead, nil
+}
+
+// CheckEncryption returns an error if the Signatory cannot encrypt tokens so
+// that every instance of a service can decrypt them. Signatories that sign
+// tokens with a private key encrypt them using the Secret, which must be set
+// explicitly rather than using the random default secret.
+func (s *Signatory) CheckEncryption() error {
+	_, err := s.encryptionKey()
+
+	return err
+}
+
+// encryptionKey returns the key used to encrypt tokens. This is the active key
+// if it holds a secret, otherwise a key is derived from the Secret.
+func (s *Signatory) encryptionKey() (*Key, error) {
+	if key := s.activeKey(); key != nil {
+		if _, ok := key.Signing.([]byte); ok {
+			return key, nil
+		}
+	}
+
+	secret, err := s.explicitSecret()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt token: %w", err)
+	}
+
+	return NewSecretKey(secret), nil
+}
+
+// decryptionKey returns the key with the given ID that can be used to decrypt a
+// token.
+func (s *Signatory) decryptionKey(id string) (*Key, error) {
+	if key, err := s.encryptionKey(); err == nil && key.ID == id {
+		return key, nil
+	}
+
+	if s.Keys != nil {
+		if key, ok := s.Keys.Lookup(id); ok {
+			if _, secret := key.Signing.([]byte); secret {
+				return key, nil
+			}
+		}
+	}
+
+	if key := NewSecretKey(s.Secret); key.ID == id {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+}
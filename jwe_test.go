@@ -0,0 +1,149 @@
+package tonic_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleSignatory_Encrypt() {
+	s := &tonic.Signatory{Secret: "secret", TTL: time.Hour, Encrypt: true}
+	token, _ := s.Issue(jwt.MapClaims{"user": "user"})
+	ctx := &gin.Context{}
+
+	// The claims are hidden from the client, but validated as normal.
+	fmt.Println(strings.Count(token, "."), strings.Contains(token, "user"))
+	fmt.Println(s.Validate(ctx, token), tonic.Get[string](ctx, "user"))
+
+	// Output:
+	// 4 false
+	// <nil> user
+}
+
+func TestSignatory_Encrypt(t *testing.T) {
+	t.Run("Encrypted tokens are validated with the same secret", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour, Encrypt: true}
+		token, err := s.Issue(jwt.MapClaims{})
+
+		assert.NoError(t, err)
+
+		other := &tonic.Signatory{Secret: "other", TTL: time.Hour}
+
+		assert.ErrorIs(t, other.Validate(&gin.Context{}, token), tonic.ErrDecryptionFailed)
+
+		plain := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+
+		assert.NoError(t, plain.Validate(&gin.Context{}, token))
+	})
+
+	t.Run("Encrypted tokens are validated with retired secrets", func(t *testing.T) {
+		t.Parallel()
+
+		old := tonic.NewSecretKey("old")
+		s := &tonic.Signatory{TTL: time.Hour, Encrypt: true, Keys: tonic.NewKeyRing(old, time.Hour)}
+		token, err := s.Issue(jwt.MapClaims{})
+
+		assert.NoError(t, err)
+
+		s.Keys.Rotate(tonic.NewSecretKey("new"))
+
+		assert.NoError(t, s.Validate(&gin.Context{}, token))
+	})
+
+	t.Run("Asymmetric signatories encrypt with the secret", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := tonic.LoadPrivateKey("testdata/ecdsa.key")
+
+		assert.NoError(t, err)
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour, PrivateKey: key, Encrypt: true}
+		token, err := s.Issue(jwt.MapClaims{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, strings.Count(token, "."))
+		assert.NoError(t, s.Validate(&gin.Context{}, token))
+	})
+
+	t.Run("Asymmetric signatories need an explicit secret", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := tonic.LoadPrivateKey("testdata/ecdsa.key")
+
+		assert.NoError(t, err)
+
+		s := &tonic.Signatory{TTL: time.Hour, PrivateKey: key, Encrypt: true}
+		_, err = s.Issue(jwt.MapClaims{})
+
+		assert.ErrorIs(t, err, tonic.ErrMissingSecret)
+
+		_, err = tonic.NewSignatory(config.Security{
+			PrivateKey: "testdata/ecdsa.key", EncryptSessions: true, SessionTTL: time.Hour,
+		})
+
+		assert.ErrorIs(t, err, tonic.ErrMissingSecret)
+
+		_, err = tonic.NewSignatory(config.Security{
+			Secret: "secret", PrivateKey: "testdata/ecdsa.key", EncryptSessions: true, SessionTTL: time.Hour,
+		})
+
+		assert.NoError(t, err)
+
+		_, err = tonic.NewSignatory(config.Security{EncryptSessions: true, SessionTTL: time.Hour})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Tampered tokens are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour, Encrypt: true}
+		token, err := s.Issue(jwt.MapClaims{})
+
+		assert.NoError(t, err)
+
+		parts := strings.Split(token, ".")
+		tag, err := base64.RawURLEncoding.DecodeString(parts[4])
+
+		assert.NoError(t, err)
+
+		tag[0] ^= 1
+		parts[4] = base64.RawURLEncoding.EncodeToString(tag)
+
+		assert.ErrorIs(t, s.Validate(&gin.Context{}, strings.Join(parts, ".")),
+			tonic.ErrDecryptionFailed)
+
+		parts = strings.Split(token, ".")
+		parts[2] = "AAAA"
+
+		assert.ErrorIs(t, s.Validate(&gin.Context{}, strings.Join(parts, ".")),
+			tonic.ErrMalformed)
+	})
+
+	t.Run("Malformed tokens are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		header := func(h string) string {
+			return base64.RawURLEncoding.EncodeToString([]byte(h))
+		}
+
+		for _, token := range []string{
+			"a.b.c.d.!",
+			"a.b.c.d.e",
+			header(`{"alg":"RSA-OAEP","enc":"A256GCM"}`) + ".b.c.d.e",
+		} {
+			assert.Error(t, s.Validate(&gin.Context{}, token), token)
+		}
+	})
+}
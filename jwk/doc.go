@@ -0,0 +1,2 @@
+// Package jwk handles JSON Web Keys as defined in RFC 7517.
+package jwk
@@ -0,0 +1,205 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Key is a JSON Web Key holding a public key.
+//
+//nolint:tagliatelle // JSON names are defined by RFC 7517 and RFC 7518.
+type Key struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+
+	// RSA parameters.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Elliptic curve and Edwards curve parameters.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+}
+
+// Set of JSON Web Keys.
+type Set struct {
+	Keys []Key `json:"keys"`
+}
+
+// JWK errors.
+var (
+	ErrUnsupportedKey = errors.New("unsupported key")
+	ErrInvalidKey     = errors.New("invalid key")
+)
+
+// Key types and curves.
+const (
+	RSA = "RSA"
+	EC  = "EC"
+	OKP = "OKP"
+
+	P256    = "P-256"
+	P384    = "P-384"
+	P521    = "P-521"
+	Ed25519 = "Ed25519"
+)
+
+// Signature is the use for keys that verify signatures.
+const Signature = "sig"
+
+// New returns a JSON Web Key for the given public key. The id and algorithm
+// are optional and will be omitted from the key if blank.
+func New(id, algorithm string, public crypto.PublicKey) (Key, error) {
+	key := Key{Use: Signature, KeyID: id, Algorithm: algorithm}
+
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		key.KeyType = RSA
+		key.N = encode(k.N.Bytes())
+		key.E = encode(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8 //nolint:gomnd // Bits to bytes.
+
+		key.KeyType = EC
+		key.Curve = k.Curve.Params().Name
+		key.X = encode(k.X.FillBytes(make([]byte, size)))
+		key.Y = encode(k.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		key.KeyType = OKP
+		key.Curve = Ed25519
+		key.X = encode(k)
+	default:
+		return key, fmt.Errorf("%w: %T", ErrUnsupportedKey, public)
+	}
+
+	return key, nil
+}
+
+// Public returns the public key held in the JSON Web Key.
+func (k Key) Public() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case RSA:
+		return k.rsa()
+	case EC:
+		return k.ecdsa()
+	case OKP:
+		return k.ed25519()
+	default:
+		return nil, fmt.Errorf("%w: key type %q", ErrUnsupportedKey, k.KeyType)
+	}
+}
+
+// Find the key with the given ID in the Set.
+func (s Set) Find(id string) (Key, bool) {
+	for _, key := range s.Keys {
+		if key.KeyID == id {
+			return key, true
+		}
+	}
+
+	return Key{}, false
+}
+
+func (k Key) rsa() (*rsa.PublicKey, error) {
+	n, err := decode(k.N)
+
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := decode(k.E)
+
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() <= 1 {
+		return nil, fmt.Errorf("%w: invalid RSA parameters", ErrInvalidKey)
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func (k Key) ecdsa() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+
+	switch k.Curve {
+	case P256:
+		curve = elliptic.P256()
+	case P384:
+		curve = elliptic.P384()
+	case P521:
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, k.Curve)
+	}
+
+	x, err := decode(k.X)
+
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := decode(k.Y)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+
+	//nolint:staticcheck // IsOnCurve is the only check available in Go 1.20.
+	if !curve.IsOnCurve(key.X, key.Y) {
+		return nil, fmt.Errorf("%w: point is not on curve %s", ErrInvalidKey, k.Curve)
+	}
+
+	return key, nil
+}
+
+func (k Key) ed25519() (ed25519.PublicKey, error) {
+	if k.Curve != Ed25519 {
+		return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, k.Curve)
+	}
+
+	x, err := decode(k.X)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: invalid Ed25519 key length", ErrInvalidKey)
+	}
+
+	return ed25519.PublicKey(x), nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err.Error())
+	}
+
+	return b, nil
+}
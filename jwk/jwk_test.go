@@ -0,0 +1,116 @@
+package jwk_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"testing"
+
+	"github.com/domdavis/tonic/jwk"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleNew() {
+	public, _, _ := ed25519.GenerateKey(rand.Reader)
+	key, err := jwk.New("id", "EdDSA", public)
+
+	fmt.Println(key.KeyType, key.Curve, key.KeyID, key.Algorithm, err)
+
+	// Output:
+	// OKP Ed25519 id EdDSA <nil>
+}
+
+func ExampleSet_Find() {
+	set := jwk.Set{Keys: []jwk.Key{{KeyID: "a"}, {KeyID: "b"}}}
+
+	_, ok := set.Find("b")
+	fmt.Println(ok)
+
+	_, ok = set.Find("c")
+	fmt.Println(ok)
+
+	// Output:
+	// true
+	// false
+}
+
+func TestKey_Public(t *testing.T) {
+	t.Run("Supported keys round trip", func(t *testing.T) {
+		t.Parallel()
+
+		r, err := rsa.GenerateKey(rand.Reader, 2048)
+
+		assert.NoError(t, err)
+
+		p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+		assert.NoError(t, err)
+
+		p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+		assert.NoError(t, err)
+
+		p521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+
+		assert.NoError(t, err)
+
+		ed, _, err := ed25519.GenerateKey(rand.Reader)
+
+		assert.NoError(t, err)
+
+		for _, public := range []any{
+			&r.PublicKey, &p256.PublicKey, &p384.PublicKey, &p521.PublicKey, ed,
+		} {
+			key, err := jwk.New("", "", public)
+
+			assert.NoError(t, err)
+
+			decoded, err := key.Public()
+
+			assert.NoError(t, err)
+			assert.Equal(t, public, decoded)
+		}
+	})
+
+	t.Run("Unsupported keys will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := jwk.New("", "", "key")
+
+		assert.ErrorIs(t, err, jwk.ErrUnsupportedKey)
+
+		_, err = jwk.Key{KeyType: "oct"}.Public()
+
+		assert.ErrorIs(t, err, jwk.ErrUnsupportedKey)
+
+		_, err = jwk.Key{KeyType: jwk.EC, Curve: "P-224"}.Public()
+
+		assert.ErrorIs(t, err, jwk.ErrUnsupportedKey)
+
+		_, err = jwk.Key{KeyType: jwk.OKP, Curve: "X25519"}.Public()
+
+		assert.ErrorIs(t, err, jwk.ErrUnsupportedKey)
+	})
+
+	t.Run("Invalid keys will error", func(t *testing.T) {
+		t.Parallel()
+
+		for _, key := range []jwk.Key{
+			{KeyType: jwk.RSA, N: "!", E: "AQAB"},
+			{KeyType: jwk.RSA, N: "AQAB", E: "!"},
+			{KeyType: jwk.RSA, N: "", E: "AQAB"},
+			{KeyType: jwk.EC, Curve: jwk.P256, X: "!", Y: "AQAB"},
+			{KeyType: jwk.EC, Curve: jwk.P256, X: "AQAB", Y: "!"},
+			{KeyType: jwk.EC, Curve: jwk.P256, X: "AQAB", Y: "AQAB"},
+			{KeyType: jwk.OKP, Curve: jwk.Ed25519, X: "!"},
+			{KeyType: jwk.OKP, Curve: jwk.Ed25519, X: "AQAB"},
+		} {
+			_, err := key.Public()
+
+			assert.ErrorIs(t, err, jwk.ErrInvalidKey)
+		}
+	})
+}
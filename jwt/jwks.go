@@ -0,0 +1,148 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/jwk"
+)
+
+// JWKS is a tonic.KeySource backed by a remote JSON Web Key Set, such as one
+// served by register.JWKS. Keys are fetched on first use and cached for the
+// TTL. A token referencing an unknown key will trigger an early refresh, but no
+// more often than the Cooldown. If a refresh fails the previously fetched keys
+// remain in use. JWKS is safe for concurrent use.
+type JWKS struct {
+	// URL of the JSON Web Key Set.
+	URL string
+
+	// Client used to fetch the key set.
+	Client *http.Client
+
+	// TTL for the cached keys.
+	TTL time.Duration
+
+	// Cooldown is the minimum time between refreshes caused by unknown keys.
+	Cooldown time.Duration
+
+	mu      sync.Mutex
+	keys    map[string]*tonic.Key
+	fetched time.Time
+}
+
+// ErrFetchFailed is returned if the key set could not be fetched.
+var ErrFetchFailed = errors.New("failed to fetch JWKS")
+
+// Defaults for a JWKS.
+const (
+	DefaultJWKSTTL      = time.Hour
+	DefaultJWKSCooldown = time.Minute
+)
+
+const jwksTimeout = 10 * time.Second
+
+// NewJWKS returns a JWKS for the given URL using the default TTL and Cooldown.
+func NewJWKS(url string) *JWKS {
+	return &JWKS{
+		URL:      url,
+		Client:   http.DefaultClient,
+		TTL:      DefaultJWKSTTL,
+		Cooldown: DefaultJWKSCooldown,
+	}
+}
+
+// Lookup the key with the given ID, refreshing the key set if needed.
+func (j *JWKS) Lookup(id string) (*tonic.Key, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key, ok := j.keys[id]
+	age := time.Since(j.fetched)
+
+	if age >= j.TTL || (!ok && age >= j.Cooldown) {
+		ctx, cancel := context.WithTimeout(context.Background(), jwksTimeout)
+		defer cancel()
+
+		//nolint:errcheck // Failures will leave the existing keys in place.
+		_ = j.refresh(ctx)
+
+		key, ok = j.keys[id]
+	}
+
+	return key, ok
+}
+
+// Refresh the key set, replacing any cached keys. Keys that cannot be used are
+// skipped.
+func (j *JWKS) Refresh(ctx context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.refresh(ctx)
+}
+
+func (j *JWKS) refresh(ctx context.Context) error {
+	j.fetched = time.Now()
+
+	set, err := j.fetch(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*tonic.Key, len(set.Keys))
+
+	for _, published := range set.Keys {
+		if published.Use != "" && published.Use != jwk.Signature {
+			continue
+		}
+
+		if key, err := tonic.ParseJWK(published); err == nil {
+			keys[key.ID] = key
+		}
+	}
+
+	j.keys = keys
+
+	return nil
+}
+
+func (j *JWKS) fetch(ctx context.Context) (jwk.Set, error) {
+	var set jwk.Set
+
+	client := j.Client
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.URL, nil)
+
+	if err != nil {
+		return set, fmt.Errorf("%w: %s", ErrFetchFailed, err.Error())
+	}
+
+	res, err := client.Do(req)
+
+	if err != nil {
+		return set, fmt.Errorf("%w: %s", ErrFetchFailed, err.Error())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return set, fmt.Errorf("%w: %s returned %d", ErrFetchFailed, j.URL, res.StatusCode)
+	}
+
+	if err = json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return set, fmt.Errorf("%w: %s", ErrFetchFailed, err.Error())
+	}
+
+	return set, nil
+}
@@ -0,0 +1,238 @@
+package jwt_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/jwt"
+	"github.com/domdavis/tonic/register"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func Issuer(t *testing.T, private string) (*tonic.Signatory, *httptest.Server) {
+	t.Helper()
+
+	signatory, err := tonic.NewSignatory(config.Security{
+		PrivateKey: private, SessionTTL: time.Hour,
+	})
+
+	assert.NoError(t, err)
+
+	signatory.Type = tonic.AccessType
+
+	router := gin.New()
+	register.JWKS(router, signatory)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return signatory, server
+}
+
+func TestAuthenticateWith(t *testing.T) {
+	const endpoint = "/ping"
+
+	t.Run("Tokens can be validated against a remote JWKS", func(t *testing.T) {
+		t.Parallel()
+
+		issuer, server := Issuer(t, "../testdata/rsa.key")
+
+		router := gin.New()
+		router.Use(jwt.AuthenticateWith(config.Security{}, jwt.NewJWKS(server.URL+register.JWKSPath)))
+		register.Ping(router)
+
+		token, err := issuer.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+
+		assert.NoError(t, err)
+
+		jwt.Set(req, token)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Tokens signed by another key are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		_, server := Issuer(t, "../testdata/rsa.key")
+		other, err := tonic.NewSignatory(config.Security{
+			PrivateKey: "../testdata/ecdsa.key", SessionTTL: time.Hour,
+		})
+
+		assert.NoError(t, err)
+
+		other.Type = tonic.AccessType
+
+		router := gin.New()
+		router.Use(jwt.AuthenticateWith(config.Security{}, jwt.NewJWKS(server.URL+register.JWKSPath)))
+		register.Ping(router)
+
+		token, err := other.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+
+		assert.NoError(t, err)
+
+		jwt.Set(req, token)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Invalid configuration will fail", func(t *testing.T) {
+		t.Parallel()
+
+		router := gin.New()
+		router.Use(jwt.AuthenticateWith(config.Security{PublicKey: "missing.pub"}, jwt.NewJWKS("")))
+		register.Ping(router)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+
+		assert.NoError(t, err)
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestJWKS_Lookup(t *testing.T) {
+	t.Run("Unknown keys trigger a refresh", func(t *testing.T) {
+		t.Parallel()
+
+		issuer, server := Issuer(t, "../testdata/rsa.key")
+		jwks := jwt.NewJWKS(server.URL + register.JWKSPath)
+		jwks.Cooldown = 0
+
+		assert.NoError(t, jwks.Refresh(context.Background()))
+
+		key, err := tonic.LoadPrivateKey("../testdata/ed25519.key")
+
+		assert.NoError(t, err)
+
+		rotated, err := tonic.NewPrivateKey(key)
+
+		assert.NoError(t, err)
+
+		issuer.Keys.Rotate(rotated)
+
+		_, ok := jwks.Lookup(rotated.ID)
+
+		assert.True(t, ok)
+	})
+
+	t.Run("Unknown keys within the cooldown do not refresh", func(t *testing.T) {
+		t.Parallel()
+
+		issuer, server := Issuer(t, "../testdata/rsa.key")
+		jwks := jwt.NewJWKS(server.URL + register.JWKSPath)
+
+		assert.NoError(t, jwks.Refresh(context.Background()))
+
+		_, ok := jwks.Lookup(issuer.Keys.Active().ID)
+
+		assert.True(t, ok)
+
+		server.Close()
+
+		_, ok = jwks.Lookup("unknown")
+
+		assert.False(t, ok)
+
+		_, ok = jwks.Lookup(issuer.Keys.Active().ID)
+
+		assert.True(t, ok)
+	})
+
+	t.Run("Failed refreshes keep the existing keys", func(t *testing.T) {
+		t.Parallel()
+
+		issuer, server := Issuer(t, "../testdata/rsa.key")
+		jwks := jwt.NewJWKS(server.URL + register.JWKSPath)
+		jwks.TTL = 0
+
+		assert.NoError(t, jwks.Refresh(context.Background()))
+
+		server.Close()
+
+		_, ok := jwks.Lookup(issuer.Keys.Active().ID)
+
+		assert.True(t, ok)
+	})
+}
+
+func TestJWKS_Refresh(t *testing.T) {
+	t.Run("Invalid URLs will error", func(t *testing.T) {
+		t.Parallel()
+
+		err := jwt.NewJWKS("://invalid").Refresh(context.Background())
+
+		assert.ErrorIs(t, err, jwt.ErrFetchFailed)
+	})
+
+	t.Run("Unreachable servers will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, server := Issuer(t, "../testdata/rsa.key")
+		server.Close()
+
+		err := jwt.NewJWKS(server.URL).Refresh(context.Background())
+
+		assert.ErrorIs(t, err, jwt.ErrFetchFailed)
+	})
+
+	t.Run("Non 200 responses will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, server := Issuer(t, "../testdata/rsa.key")
+
+		err := jwt.NewJWKS(server.URL + "/missing").Refresh(context.Background())
+
+		assert.ErrorIs(t, err, jwt.ErrFetchFailed)
+	})
+
+	t.Run("Invalid key sets will error", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("not json"))
+		}))
+		t.Cleanup(server.Close)
+
+		err := jwt.NewJWKS(server.URL).Refresh(context.Background())
+
+		assert.ErrorIs(t, err, jwt.ErrFetchFailed)
+	})
+
+	t.Run("Unusable keys are skipped", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`{"keys":[{"kty":"oct","kid":"a"},{"kty":"RSA","use":"enc","kid":"b"}]}`))
+		}))
+		t.Cleanup(server.Close)
+
+		jwks := &jwt.JWKS{URL: server.URL, TTL: time.Hour}
+
+		assert.NoError(t, jwks.Refresh(context.Background()))
+
+		_, ok := jwks.Lookup("a")
+
+		assert.False(t, ok)
+	})
+}
@@ -1,6 +1,7 @@
 package jwt
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -19,6 +20,24 @@ const Header = "Authorization"
 // Prefix is applied/removed from the front of the JWT token.
 const Prefix = "Bearer"
 
+// ChallengeHeader is set on responses to requests that fail authentication.
+const ChallengeHeader = "WWW-Authenticate"
+
+// InvalidToken is the RFC 6750 error code sent in the ChallengeHeader when a
+// token fails validation.
+const InvalidToken = "invalid_token"
+
+// reasons a token can fail validation, used to describe the failure in the
+// ChallengeHeader.
+//
+//nolint:gochecknoglobals // Effectively a constant.
+var reasons = []error{
+	tonic.ErrExpired, tonic.ErrNotYetValid, tonic.ErrRevoked,
+	tonic.ErrInvalidSignature, tonic.ErrWrongAlgorithm, tonic.ErrUnknownKey,
+	tonic.ErrWrongType, tonic.ErrInvalidIssuer, tonic.ErrInvalidAudience,
+	tonic.ErrInvalidClaim, tonic.ErrMalformed,
+}
+
 // Sign a response with an authentication token. The token will contain the
 // authorisation claims taken from the context, and a TLL. Sign will send the
 // response to the client so no further action is required once called.
@@ -26,8 +45,18 @@ const Prefix = "Bearer"
 //
 // The token can be used with the Authenticate middleware handler.
 func Sign(c *gin.Context, security config.Security, claims ...string) {
-	signatory := &tonic.Signatory{TTL: security.SessionTTL, Secret: security.Secret}
-	signatory.Initialise()
+	signatory, err := bearer(security)
+
+	if err != nil {
+		//nolint:errcheck // Gin is handling this for us.
+		_ = c.Error(fmt.Errorf("failed to sign token: %w", err))
+		c.String(http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError))
+
+		return
+	}
+
+	signatory.Revocations = tonic.Revocations(c)
 
 	if token, err := signatory.Sign(c, claims...); err != nil {
 		//nolint:errcheck // Gin is handling this for us.
@@ -48,24 +77,195 @@ func Set(r *http.Request, token string) {
 	r.Header.Set(Header, token)
 }
 
+// Logout revokes the token held in the Authorization header so it cannot be
+// used again. Logout requires a RevocationStore to have been set on the context
+// using tonic.Revocable.
+func Logout(c *gin.Context, security config.Security) error {
+	signatory, err := bearer(security)
+
+	if err == nil {
+		signatory.Revocations = tonic.Revocations(c)
+		err = signatory.Revoke(token(c))
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	return nil
+}
+
+// Authenticator authenticates requests using a bearer token held in the
+// Authorization header. An Authenticator can be used with an auth.Chain, or
+// using the Authenticate middleware.
+type Authenticator struct {
+	signatory *tonic.Signatory
+	err       error
+}
+
+// Method is the authentication method used by an Authenticator.
+const Method = "bearer"
+
+// NewAuthenticator returns an Authenticator using the given security settings.
+// Session cookie tokens will not authenticate unless shared tokens are enabled
+// in the security settings.
+func NewAuthenticator(security config.Security) *Authenticator {
+	signatory, err := bearer(security)
+
+	if err != nil {
+		err = fmt.Errorf("%w: %w", tonic.ErrMisconfigured, err)
+	}
+
+	return &Authenticator{signatory: signatory, err: err}
+}
+
+// NewAuthenticatorWith returns an Authenticator that validates tokens using
+// keys from the given source rather than the configured secret or keys. This
+// allows tokens signed by another service to be validated, typically using a
+// JWKS.
+func NewAuthenticatorWith(security config.Security, source tonic.KeySource) *Authenticator {
+	a := NewAuthenticator(security)
+
+	if a.err == nil {
+		a.signatory.Source = source
+	}
+
+	return a
+}
+
+// Method returns the authentication method used by the Authenticator.
+func (a *Authenticator) Method() string {
+	return Method
+}
+
+// Authenticate the request, setting the claims held in the token on the
+// context. tonic.ErrMissingToken is returned if the request doesn't have a
+// token, and tonic.ErrMisconfigured if the security settings are invalid.
+// Revoked tokens will not authenticate if a RevocationStore has been set
+// on the context using tonic.Revocable.
+func (a *Authenticator) Authenticate(c *gin.Context) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	revocable := *a.signatory
+	revocable.Revocations = tonic.Revocations(c)
+
+	if err := revocable.Validate(c, token(c)); err != nil {
+		return fmt.Errorf("failed to authenticate token: %w", err)
+	}
+
+	return nil
+}
+
+// Challenge returns the WWW-Authenticate header value describing why the
+// request failed to authenticate, as defined in RFC 6750.
+func (a *Authenticator) Challenge(err error) string {
+	return challenge(err)
+}
+
 // Authenticate a request by checking for a JWT token in the Authorisation
 // header. Authenticate uses sensible defaults if no configuration is set, and
 // will use a generated secret if none is set. Failure to authenticate will
-// abort the middleware chain and return http.StatusUnauthorized.
+// abort the middleware chain and return http.StatusUnauthorized. If the
+// configured keys cannot be loaded then every request will fail with
+// http.StatusInternalServerError. Session cookie tokens will not authenticate
+// unless shared tokens are enabled in the security settings. Revoked tokens
+// will not authenticate if a RevocationStore has been set on the context using
+// tonic.Revocable. The reason a token failed to authenticate is passed to
+// c.Error and described in the WWW-Authenticate header.
 func Authenticate(security config.Security) gin.HandlerFunc {
-	signatory := &tonic.Signatory{Secret: security.Secret}
-	signatory.Initialise()
+	return authenticate(NewAuthenticator(security))
+}
+
+// AuthenticateWith authenticates requests in the same way as Authenticate, but
+// validates tokens using keys from the given source rather than the configured
+// secret or keys. This allows tokens signed by another service to be validated,
+// typically using a JWKS.
+func AuthenticateWith(security config.Security, source tonic.KeySource) gin.HandlerFunc {
+	return authenticate(NewAuthenticatorWith(security, source))
+}
+
+// AccessIssuer returns a Signatory issuing access tokens for use with
+// register.TokenEndpoint. The tokens are signed with the same keys used by
+// Authenticate, and can be revoked using register.RevocationEndpoint if a
+// RevocationStore is given. The store may be nil.
+func AccessIssuer(security config.Security, revocations tonic.RevocationStore) (*tonic.Signatory, error) {
+	signatory, err := bearer(security)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signatory.Revocations = revocations
+
+	return signatory, nil
+}
+
+// bearer returns a Signatory for bearer access tokens, sharing its keys with
+// every other Signatory for the security settings.
+func bearer(security config.Security) (*tonic.Signatory, error) {
+	signatory, err := tonic.SharedSignatory(security)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid security settings: %w", err)
+	}
+
+	signatory.Type = tonic.AccessType
 
+	if security.SharedTokens {
+		signatory.AcceptTypes = []string{tonic.SessionType}
+	}
+
+	return signatory, nil
+}
+
+func authenticate(a *Authenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token := c.GetHeader(Header)
-		token = strings.TrimPrefix(token, Prefix)
-		token = strings.TrimSpace(token)
+		if a.err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(fmt.Errorf("failed to authenticate token: %w", a.err))
+			c.Abort()
+			c.String(http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError))
 
-		if !signatory.Validate(c, token) {
+			return
+		}
+
+		if err := a.Authenticate(c); err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(err)
 			c.Abort()
+			c.Header(ChallengeHeader, a.Challenge(err))
 			c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
 		} else {
 			c.Next()
 		}
 	}
 }
+
+// challenge returns the WWW-Authenticate header value for the given validation
+// error, as defined in RFC 6750. Requests without a token are not given an
+// error code.
+func challenge(err error) string {
+	if errors.Is(err, tonic.ErrMissingToken) {
+		return Prefix
+	}
+
+	description := "invalid token"
+
+	for _, reason := range reasons {
+		if errors.Is(err, reason) {
+			description = reason.Error()
+
+			break
+		}
+	}
+
+	return fmt.Sprintf("%s error=%q, error_description=%q", Prefix, InvalidToken, description)
+}
+
+// token returns the JWT held in the Authorization header.
+func token(c *gin.Context) string {
+	return strings.TrimSpace(strings.TrimPrefix(c.GetHeader(Header), Prefix))
+}
@@ -1,5 +1,6 @@
 package jwt_test
 
+//nolint:importas // avoiding collision with the tonic jwt package.
 import (
 	"fmt"
 	"io"
@@ -10,10 +11,12 @@ import (
 
 	"github.com/domdavis/tonic"
 	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/cookie"
 	"github.com/domdavis/tonic/jwt"
 	"github.com/domdavis/tonic/middleware"
 	"github.com/domdavis/tonic/register"
 	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v4"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -99,6 +102,19 @@ func TestSign(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
 		assert.NotEmpty(t, c.Errors)
 	})
+
+	t.Run("An invalid key will error", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		s := config.Security{SessionTTL: time.Hour, PrivateKey: "missing.key"}
+
+		jwt.Sign(c, s)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+		assert.NotEmpty(t, c.Errors)
+	})
 }
 
 func TestAuthenticate(t *testing.T) {
@@ -122,7 +138,9 @@ func TestAuthenticate(t *testing.T) {
 
 		assert.NoError(t, err)
 
-		signatory := &tonic.Signatory{TTL: security.SessionTTL, Secret: security.Secret}
+		signatory := &tonic.Signatory{
+			TTL: security.SessionTTL, Secret: security.Secret, Type: tonic.AccessType,
+		}
 		signatory.Initialise()
 
 		token, err := signatory.Sign(&gin.Context{})
@@ -160,4 +178,248 @@ func TestAuthenticate(t *testing.T) {
 
 		assert.Equal(t, http.StatusUnauthorized, w.Result().StatusCode)
 	})
+
+	t.Run("Tokens signed with a private key validate with the public key", func(t *testing.T) {
+		t.Parallel()
+
+		router := gin.New()
+		router.Use(jwt.Authenticate(config.Security{PublicKey: "../testdata/ed25519.pub"}))
+		register.Ping(router)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		jwt.Sign(c, config.Security{
+			SessionTTL: time.Hour, PrivateKey: "../testdata/ed25519.key",
+		})
+
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+
+		assert.NoError(t, err)
+
+		jwt.Set(req, w.Body.String())
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	})
+
+	t.Run("An invalid key will fail", func(t *testing.T) {
+		t.Parallel()
+
+		router := gin.New()
+		router.Use(jwt.Authenticate(config.Security{PublicKey: "missing.pub"}))
+		register.Ping(router)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+
+		assert.NoError(t, err)
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+	})
+
+	t.Run("Session cookies are rejected unless tokens are shared", func(t *testing.T) {
+		t.Parallel()
+
+		security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		assert.NoError(t, cookie.Drop(c, security))
+
+		session := w.Result().Cookies()[0].Value
+
+		for shared, code := range map[bool]int{
+			false: http.StatusUnauthorized,
+			true:  http.StatusOK,
+		} {
+			security.SharedTokens = shared
+
+			router := gin.New()
+			router.Use(jwt.Authenticate(security))
+			register.Ping(router)
+
+			req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+
+			assert.NoError(t, err)
+
+			jwt.Set(req, session)
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, code, w.Code)
+		}
+	})
+}
+
+func TestAccessIssuer(t *testing.T) {
+	t.Run("Issued tokens are accepted by Authenticate", func(t *testing.T) {
+		t.Parallel()
+
+		// The random secret is shared with Authenticate.
+		security := config.Security{SessionTTL: time.Hour}
+		issuer, err := jwt.AccessIssuer(security, nil)
+
+		assert.NoError(t, err)
+
+		token, err := issuer.Issue(gojwt.MapClaims{tonic.SubjectClaim: "client"})
+
+		assert.NoError(t, err)
+
+		router := gin.New()
+		router.GET("/", jwt.Authenticate(security), func(c *gin.Context) {
+			c.String(http.StatusOK, tonic.Get[string](c, tonic.SubjectClaim))
+		})
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		jwt.Set(req, token)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "client", w.Body.String())
+	})
+
+	t.Run("Invalid settings will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := jwt.AccessIssuer(config.Security{SessionTTL: time.Hour, PrivateKey: "missing.key"}, nil)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+	t.Run("Logged out tokens will not authenticate", func(t *testing.T) {
+		t.Parallel()
+
+		store := tonic.NewMemoryRevocations()
+		router := gin.New()
+		router.Use(tonic.Revocable(store))
+		router.GET("/login", func(c *gin.Context) {
+			c.Set(tonic.SubjectClaim, "user")
+			jwt.Sign(c, security, tonic.SubjectClaim)
+		})
+		router.GET("/logout", func(c *gin.Context) {
+			assert.NoError(t, jwt.Logout(c, security))
+		})
+		router.GET("/ping", jwt.Authenticate(security))
+
+		request := func(path, token string) int {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, path, nil)
+
+			assert.NoError(t, err)
+
+			jwt.Set(req, token)
+			router.ServeHTTP(w, req)
+
+			return w.Code
+		}
+
+		login := func() string {
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, "/login", nil)
+
+			assert.NoError(t, err)
+
+			router.ServeHTTP(w, req)
+
+			return w.Body.String()
+		}
+
+		token, other := login(), login()
+
+		assert.Equal(t, http.StatusOK, request("/ping", token))
+		assert.Equal(t, http.StatusOK, request("/logout", token))
+		assert.Equal(t, http.StatusUnauthorized, request("/ping", token))
+		assert.Equal(t, http.StatusOK, request("/ping", other))
+
+		// Log out everywhere.
+		assert.NoError(t, store.RevokeAll("user"))
+		assert.Equal(t, http.StatusUnauthorized, request("/ping", other))
+		assert.Equal(t, http.StatusOK, request("/ping", login()))
+	})
+
+	t.Run("Logout without a store will error", func(t *testing.T) {
+		t.Parallel()
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+
+		assert.ErrorIs(t, jwt.Logout(c, security), tonic.ErrNoRevocationStore)
+	})
+}
+
+func TestAuthenticate_challenge(t *testing.T) {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+	expired := gojwt.NewWithClaims(gojwt.SigningMethodHS512, gojwt.MapClaims{
+		tonic.ExpiryClaim: time.Now().Add(-time.Hour).Unix(),
+	})
+	expired.Header["typ"] = tonic.AccessType
+
+	old, err := expired.SignedString([]byte(security.Secret))
+
+	assert.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		token     string
+		challenge string
+		err       error
+	}{
+		"Requests without a token are not given an error code": {
+			challenge: "Bearer",
+			err:       tonic.ErrMissingToken,
+		},
+		"Malformed tokens are described": {
+			token:     "token",
+			challenge: `Bearer error="invalid_token", error_description="malformed token"`,
+			err:       tonic.ErrMalformed,
+		},
+		"Expired tokens are described": {
+			token:     old,
+			challenge: `Bearer error="invalid_token", error_description="token has expired"`,
+			err:       tonic.ErrExpired,
+		},
+	} {
+		tc := tc
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var errs []*gin.Error
+
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				c.Next()
+				errs = c.Errors
+			})
+			router.Use(jwt.Authenticate(security))
+			register.Ping(router)
+
+			w := httptest.NewRecorder()
+			req, err := http.NewRequest(http.MethodGet, "/ping", nil)
+
+			assert.NoError(t, err)
+
+			if tc.token != "" {
+				jwt.Set(req, tc.token)
+			}
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Equal(t, tc.challenge, w.Header().Get(jwt.ChallengeHeader))
+			assert.Len(t, errs, 1)
+			assert.ErrorIs(t, errs[0], tc.err)
+		})
+	}
 }
@@ -0,0 +1,335 @@
+package jwt
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/register"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// FamilyClaim holds the refresh token family on refresh tokens.
+const FamilyClaim = "fam"
+
+// Tokens is the response sent by Pair and Refresh. It is the OAuth 2.0 token
+// response defined in RFC 6749, as sent by register.TokenEndpoint.
+type Tokens = register.TokenResponse
+
+// Refresher issues refresh tokens for register.TokenEndpoint. Each refresh
+// token belongs to a family tracked in a RefreshStore, in the same way as the
+// tokens issued by Pair, so a token can only be rotated once and reusing a
+// token revokes its family.
+type Refresher struct {
+	signatory *tonic.Signatory
+	store     RefreshStore
+}
+
+// refreshRequest holds the refresh token sent to the Refresh handler.
+//
+//nolint:tagliatelle // Field names are defined by RFC 6749.
+type refreshRequest struct {
+	RefreshToken string `form:"refresh_token" json:"refresh_token"`
+}
+
+// Pair responds with an access token and a refresh token. Both tokens contain
+// the authorisation claims taken from the context. The access token expires
+// after the SessionTTL and the refresh token after the RefreshTTL. The refresh
+// token starts a new family in the given store and can be exchanged for a new
+// pair using the Refresh handler. Pair will send the response to the client so
+// no further action is required once called. The response is never cached.
+// Failure to issue the tokens will result in a 500 error response.
+func Pair(c *gin.Context, security config.Security, store RefreshStore, claims ...string) {
+	noStore(c)
+
+	payload := jwt.MapClaims{}
+
+	for _, claim := range claims {
+		payload[claim], _ = c.Get(claim)
+	}
+
+	record := func(id string, expires time.Time) (string, error) {
+		name := tonic.GenerateID()
+
+		return name, store.Issue(name, id, expires)
+	}
+
+	tokens, err := pair(security, tonic.Revocations(c), payload, record)
+
+	if err != nil {
+		//nolint:errcheck // Gin is handling this for us.
+		_ = c.Error(fmt.Errorf("failed to issue tokens: %w", err))
+		c.String(http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh returns a handler that exchanges a refresh token, sent as the
+// refresh_token form or JSON field, for a new access and refresh token pair.
+// Each refresh token can only be used once. Reusing a refresh token revokes
+// every token in its family, forcing the client to authenticate again. Invalid,
+// reused, or revoked refresh tokens will result in http.StatusUnauthorized. If
+// a RevocationStore has been set on the context using tonic.Revocable then
+// refresh tokens for a subject revoked with RevokeAll will also be rejected.
+// Responses are never cached.
+func Refresh(security config.Security, store RefreshStore) gin.HandlerFunc {
+	signatory, err := refresher(security)
+
+	return func(c *gin.Context) {
+		var request refreshRequest
+
+		noStore(c)
+
+		if err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(fmt.Errorf("failed to refresh token: %w", err))
+			c.String(http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError))
+
+			return
+		}
+
+		if err := c.ShouldBind(&request); err != nil || request.RefreshToken == "" {
+			c.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+
+			return
+		}
+
+		revocable := *signatory
+		revocable.Revocations = tonic.Revocations(c)
+
+		claims, err := revocable.Parse(request.RefreshToken)
+
+		if err != nil {
+			c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+
+			return
+		}
+
+		name, _ := claims[FamilyClaim].(string)
+		used, _ := claims[tonic.IDClaim].(string)
+		payload := jwt.MapClaims(tonic.Private(claims))
+
+		delete(payload, FamilyClaim)
+
+		record := func(id string, expires time.Time) (string, error) {
+			return name, store.Rotate(name, used, id, expires)
+		}
+
+		tokens, err := pair(security, revocable.Revocations, payload, record)
+
+		if err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(fmt.Errorf("failed to refresh token: %w", err))
+			c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+
+			return
+		}
+
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// noStore stops the tokens in the response from being cached, as required by
+// RFC 6749.
+func noStore(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+}
+
+// pair issues an access and refresh token for the given claims. The record
+// function is called with the ID of the new refresh token and must return the
+// family it belongs to.
+func pair(security config.Security, store tonic.RevocationStore, claims jwt.MapClaims,
+	record func(id string, expires time.Time) (string, error)) (Tokens, error) {
+	access, err := bearer(security)
+
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	refresh, err := refresher(security)
+
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	access.Revocations = store
+	refresh.Revocations = store
+
+	accessToken, err := access.Issue(claims)
+
+	if err != nil {
+		return Tokens{}, fmt.Errorf("failed to issue access token: %w", err)
+	}
+
+	id := tonic.GenerateID()
+	name, err := record(id, time.Now().Add(refresh.TTL))
+
+	if err != nil {
+		return Tokens{}, fmt.Errorf("failed to record refresh token: %w", err)
+	}
+
+	refreshToken, err := issueRefresh(refresh, claims, id, name)
+
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    Prefix,
+		ExpiresIn:    int(access.TTL.Round(time.Second).Seconds()),
+	}, nil
+}
+
+// refreshers holds the Signatories for refresh tokens. These have their own
+// KeyRing since retired keys must outlive the refresh tokens they signed. A
+// RefreshTTL that isn't positive is rejected rather than keeping retired keys
+// forever.
+//
+//nolint:gochecknoglobals // Needs to be global so the Signatories are shared.
+var refreshers = tonic.NewSignatories(func(security config.Security) (*tonic.Signatory, error) {
+	if security.RefreshTTL <= 0 {
+		return nil, fmt.Errorf("%w: refresh TTL %v", tonic.ErrInvalidTTL, security.RefreshTTL)
+	}
+
+	signatory, err := tonic.NewSignatory(security)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signatory.Keys.Grace = security.RefreshTTL
+
+	return signatory, nil
+})
+
+// RefreshIssuer returns a Refresher for use with register.TokenEndpoint. The
+// refresh tokens expire after the RefreshTTL, which must be positive, and are
+// tracked in the given RefreshStore, which is required. Refresh tokens can be
+// revoked using register.RevocationEndpoint if a RevocationStore is given. The
+// RevocationStore may be nil.
+func RefreshIssuer(security config.Security, store RefreshStore,
+	revocations tonic.RevocationStore) (*Refresher, error) {
+	if store == nil {
+		return nil, fmt.Errorf("refresh tokens cannot be rotated: %w", ErrNoRefreshStore)
+	}
+
+	signatory, err := refresher(security)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signatory.Revocations = revocations
+
+	return &Refresher{signatory: signatory, store: store}, nil
+}
+
+// Issue the first refresh token in a new family for the given claims.
+func (r *Refresher) Issue(claims jwt.MapClaims) (string, error) {
+	id, family := tonic.GenerateID(), tonic.GenerateID()
+
+	if err := r.store.Issue(family, id, time.Now().Add(r.signatory.TTL)); err != nil {
+		return "", fmt.Errorf("failed to record refresh token: %w", err)
+	}
+
+	return issueRefresh(r.signatory, claims, id, family)
+}
+
+// Rotate the refresh token, returning the next token in its family for the
+// given claims. Rotating a token that has already been rotated revokes its
+// family and returns an error wrapping ErrRefreshReused.
+func (r *Refresher) Rotate(token string, claims jwt.MapClaims) (string, error) {
+	used, err := r.Parse(token)
+
+	if err != nil {
+		return "", err
+	}
+
+	family, _ := used[FamilyClaim].(string)
+	id, _ := used[tonic.IDClaim].(string)
+	next := tonic.GenerateID()
+
+	if err = r.store.Rotate(family, id, next, time.Now().Add(r.signatory.TTL)); err != nil {
+		return "", fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	return issueRefresh(r.signatory, claims, next, family)
+}
+
+// Parse and validate a refresh token, returning its claims.
+func (r *Refresher) Parse(token string) (jwt.MapClaims, error) {
+	claims, err := r.signatory.Parse(token)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh token: %w", err)
+	}
+
+	return claims, nil
+}
+
+// Revoke a valid refresh token so it can no longer be used. Revoke requires
+// the Refresher to have a RevocationStore.
+func (r *Refresher) Revoke(token string) error {
+	if err := r.signatory.Revoke(token); err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// Lifetime of the refresh tokens.
+func (r *Refresher) Lifetime() time.Duration {
+	return r.signatory.Lifetime()
+}
+
+// refresher returns a Signatory for refresh tokens, sharing its keys with every
+// other refresh token Signatory for the security settings. An error wrapping
+// tonic.ErrInvalidTTL is returned if the RefreshTTL isn't positive.
+func refresher(security config.Security) (*tonic.Signatory, error) {
+	if security.RefreshTTL <= 0 {
+		return nil, fmt.Errorf("%w: refresh TTL %v", tonic.ErrInvalidTTL, security.RefreshTTL)
+	}
+
+	signatory, err := refreshers.Get(security)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid security settings: %w", err)
+	}
+
+	signatory.Type = tonic.RefreshType
+	signatory.TTL = security.RefreshTTL
+
+	return signatory, nil
+}
+
+// issueRefresh issues the refresh token with the given ID in the family,
+// carrying the claims.
+func issueRefresh(signatory *tonic.Signatory, claims jwt.MapClaims, id, family string) (string, error) {
+	payload := jwt.MapClaims{}
+
+	for k, v := range claims {
+		payload[k] = v
+	}
+
+	payload[tonic.IDClaim] = id
+	payload[FamilyClaim] = family
+
+	token, err := signatory.Issue(payload)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to issue refresh token: %w", err)
+	}
+
+	return token, nil
+}
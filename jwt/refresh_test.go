@@ -0,0 +1,371 @@
+package jwt_test
+
+//nolint:importas // avoiding collision with the tonic jwt package.
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/jwt"
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func Exchange(t *testing.T, router *gin.Engine, body string) (int, jwt.Tokens) {
+	t.Helper()
+
+	var tokens jwt.Tokens
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodPost, "/refresh", strings.NewReader(body))
+
+	assert.NoError(t, err)
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	router.ServeHTTP(w, req)
+
+	if w.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+	}
+
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	return w.Code, tokens
+}
+
+func ExamplePair() {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	s := config.Security{Secret: "secret", SessionTTL: time.Hour, RefreshTTL: time.Hour * 24}
+
+	c.Set("user", "user")
+
+	// Respond with an access and refresh token holding the "user" claim.
+	jwt.Pair(c, s, jwt.NewMemoryStore(), "user")
+
+	var tokens jwt.Tokens
+
+	_ = json.Unmarshal(w.Body.Bytes(), &tokens)
+
+	fmt.Println(w.Code, tokens.TokenType, tokens.ExpiresIn, w.Header().Get("Cache-Control"))
+
+	// Output:
+	// 200 Bearer 3600 no-store
+}
+
+func TestPair(t *testing.T) {
+	t.Run("Invalid settings will fail", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		s := config.Security{SessionTTL: time.Hour, PrivateKey: "testdata/missing.key"}
+
+		jwt.Pair(c, s, jwt.NewMemoryStore())
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+
+	t.Run("An invalid refresh TTL will fail", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		s := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+		jwt.Pair(c, s, jwt.NewMemoryStore())
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.ErrorIs(t, c.Errors.Last(), tonic.ErrInvalidTTL)
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	security := config.Security{
+		Secret: "secret", SessionTTL: time.Hour, RefreshTTL: time.Hour * 24,
+	}
+
+	setup := func(t *testing.T) (*gin.Engine, jwt.Tokens) {
+		t.Helper()
+
+		var tokens jwt.Tokens
+
+		store := jwt.NewMemoryStore()
+		router := gin.New()
+		router.POST("/refresh", jwt.Refresh(security, store))
+		router.GET("/ping", jwt.Authenticate(security), func(c *gin.Context) {
+			c.String(http.StatusOK, c.GetString("user"))
+		})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		c.Set("user", "user")
+		jwt.Pair(c, security, store, "user")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+
+		return router, tokens
+	}
+
+	t.Run("Refresh tokens can be exchanged for a new pair", func(t *testing.T) {
+		t.Parallel()
+
+		router, tokens := setup(t)
+		code, refreshed := Exchange(t, router, url.Values{
+			"refresh_token": {tokens.RefreshToken},
+		}.Encode())
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/ping", nil)
+
+		assert.NoError(t, err)
+
+		jwt.Set(req, refreshed.AccessToken)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user", w.Body.String())
+
+		code, _ = Exchange(t, router, url.Values{
+			"refresh_token": {refreshed.RefreshToken},
+		}.Encode())
+
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("Reusing a refresh token revokes the family", func(t *testing.T) {
+		t.Parallel()
+
+		router, tokens := setup(t)
+		body := url.Values{"refresh_token": {tokens.RefreshToken}}.Encode()
+		code, refreshed := Exchange(t, router, body)
+
+		assert.Equal(t, http.StatusOK, code)
+
+		code, _ = Exchange(t, router, body)
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		code, _ = Exchange(t, router, url.Values{
+			"refresh_token": {refreshed.RefreshToken},
+		}.Encode())
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("Access tokens cannot be used as refresh tokens", func(t *testing.T) {
+		t.Parallel()
+
+		router, tokens := setup(t)
+		code, _ := Exchange(t, router, url.Values{
+			"refresh_token": {tokens.AccessToken},
+		}.Encode())
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("Refresh tokens cannot be used as access tokens", func(t *testing.T) {
+		t.Parallel()
+
+		router, tokens := setup(t)
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, "/ping", nil)
+
+		assert.NoError(t, err)
+
+		jwt.Set(req, tokens.RefreshToken)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("A missing refresh token is a bad request", func(t *testing.T) {
+		t.Parallel()
+
+		router, _ := setup(t)
+		code, _ := Exchange(t, router, "")
+
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("Invalid settings will fail", func(t *testing.T) {
+		t.Parallel()
+
+		router := gin.New()
+		router.POST("/refresh", jwt.Refresh(config.Security{
+			PrivateKey: "testdata/missing.key",
+		}, jwt.NewMemoryStore()))
+
+		code, _ := Exchange(t, router, "refresh_token=token")
+
+		assert.Equal(t, http.StatusInternalServerError, code)
+	})
+}
+
+func TestMemoryStore_Rotate(t *testing.T) {
+	t.Run("Unknown families are revoked", func(t *testing.T) {
+		t.Parallel()
+
+		err := jwt.NewMemoryStore().Rotate("family", "a", "b", time.Now().Add(time.Hour))
+
+		assert.ErrorIs(t, err, jwt.ErrRefreshRevoked)
+	})
+
+	t.Run("Expired families are revoked", func(t *testing.T) {
+		t.Parallel()
+
+		store := jwt.NewMemoryStore()
+
+		assert.NoError(t, store.Issue("family", "a", time.Now().Add(-time.Second)))
+
+		err := store.Rotate("family", "a", "b", time.Now().Add(time.Hour))
+
+		assert.ErrorIs(t, err, jwt.ErrRefreshRevoked)
+	})
+
+	t.Run("Reused tokens revoke the family", func(t *testing.T) {
+		t.Parallel()
+
+		store := jwt.NewMemoryStore()
+		expires := time.Now().Add(time.Hour)
+
+		assert.NoError(t, store.Issue("family", "a", expires))
+		assert.NoError(t, store.Rotate("family", "a", "b", expires))
+		assert.ErrorIs(t, store.Rotate("family", "a", "c", expires), jwt.ErrRefreshReused)
+		assert.ErrorIs(t, store.Rotate("family", "b", "c", expires), jwt.ErrRefreshRevoked)
+	})
+}
+
+func TestRefresh_revocations(t *testing.T) {
+	t.Run("Refresh tokens are revoked by RevokeAll", func(t *testing.T) {
+		t.Parallel()
+
+		security := config.Security{
+			Secret: "secret", SessionTTL: time.Hour, RefreshTTL: time.Hour,
+		}
+
+		var tokens jwt.Tokens
+
+		revocations := tonic.NewMemoryRevocations()
+		store := jwt.NewMemoryStore()
+		router := gin.New()
+		router.Use(tonic.Revocable(revocations))
+		router.POST("/refresh", jwt.Refresh(security, store))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		tonic.Revocable(revocations)(c)
+		c.Set(tonic.SubjectClaim, "user")
+		jwt.Pair(c, security, store, tonic.SubjectClaim)
+
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+		assert.NoError(t, revocations.RevokeAll("user"))
+
+		code, _ := Exchange(t, router, url.Values{
+			"refresh_token": {tokens.RefreshToken},
+		}.Encode())
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+}
+
+func TestRefreshIssuer(t *testing.T) {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour, RefreshTTL: time.Hour * 24}
+
+	t.Run("Issued tokens are refresh tokens", func(t *testing.T) {
+		t.Parallel()
+
+		revocations := tonic.NewMemoryRevocations()
+		issuer, err := jwt.RefreshIssuer(security, jwt.NewMemoryStore(), revocations)
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Hour*24, issuer.Lifetime())
+
+		token, err := issuer.Issue(nil)
+
+		assert.NoError(t, err)
+
+		access, err := jwt.AccessIssuer(security, revocations)
+
+		assert.NoError(t, err)
+
+		_, err = access.Parse(token)
+
+		assert.ErrorIs(t, err, tonic.ErrWrongType)
+		assert.NoError(t, issuer.Revoke(token))
+
+		_, err = issuer.Parse(token)
+
+		assert.ErrorIs(t, err, tonic.ErrRevoked)
+	})
+
+	t.Run("Reusing a refresh token revokes its family", func(t *testing.T) {
+		t.Parallel()
+
+		issuer, err := jwt.RefreshIssuer(security, jwt.NewMemoryStore(), nil)
+
+		assert.NoError(t, err)
+
+		first, err := issuer.Issue(gojwt.MapClaims{tonic.SubjectClaim: "client"})
+
+		assert.NoError(t, err)
+
+		second, err := issuer.Rotate(first, gojwt.MapClaims{tonic.SubjectClaim: "client"})
+
+		assert.NoError(t, err)
+
+		claims, err := issuer.Parse(second)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "client", claims[tonic.SubjectClaim])
+
+		_, err = issuer.Rotate(first, nil)
+
+		assert.ErrorIs(t, err, jwt.ErrRefreshReused)
+
+		_, err = issuer.Rotate(second, nil)
+
+		assert.ErrorIs(t, err, jwt.ErrRefreshRevoked)
+		assert.ErrorIs(t, issuer.Revoke(second), tonic.ErrNoRevocationStore)
+	})
+
+	t.Run("A refresh store is required", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := jwt.RefreshIssuer(security, nil, nil)
+
+		assert.ErrorIs(t, err, jwt.ErrNoRefreshStore)
+
+		s := security
+		s.PrivateKey = "missing.key"
+		_, err = jwt.RefreshIssuer(s, jwt.NewMemoryStore(), nil)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("A refresh TTL is required", func(t *testing.T) {
+		t.Parallel()
+
+		for _, ttl := range []time.Duration{0, -time.Hour} {
+			s := security
+			s.RefreshTTL = ttl
+			_, err := jwt.RefreshIssuer(s, jwt.NewMemoryStore(), nil)
+
+			assert.ErrorIs(t, err, tonic.ErrInvalidTTL)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A RefreshStore tracks the refresh tokens that have been issued. Refresh
+// tokens belong to a family, which starts when a token pair is issued by Pair
+// and continues through each refresh. Only the most recent refresh token in a
+// family can be used. If an older token is presented then it has been stolen
+// or replayed, and the whole family is revoked.
+type RefreshStore interface {
+	// Issue records the first refresh token in a new family.
+	Issue(family, id string, expires time.Time) error
+
+	// Rotate replaces the used refresh token with the next token in the
+	// family. If used is not the current token for the family then the family
+	// must be revoked and ErrRefreshReused returned. If the family is unknown
+	// then ErrRefreshRevoked must be returned.
+	Rotate(family, used, next string, expires time.Time) error
+}
+
+// RefreshStore errors.
+var (
+	ErrRefreshReused  = errors.New("refresh token reused")
+	ErrRefreshRevoked = errors.New("refresh token revoked")
+	ErrNoRefreshStore = errors.New("no refresh store")
+)
+
+// MemoryStore is a RefreshStore held in memory. Expired families are removed
+// whenever the store is used. A MemoryStore is safe for concurrent use, but
+// cannot be shared between instances of a service.
+type MemoryStore struct {
+	mu       sync.Mutex
+	families map[string]family
+}
+
+type family struct {
+	current string
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{families: map[string]family{}}
+}
+
+// Issue records the first refresh token in a new family.
+func (m *MemoryStore) Issue(name, id string, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep()
+	m.families[name] = family{current: id, expires: expires}
+
+	return nil
+}
+
+// Rotate replaces the used refresh token with the next token in the family,
+// revoking the family if the used token is not the current token.
+func (m *MemoryStore) Rotate(name, used, next string, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep()
+
+	f, ok := m.families[name]
+
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: family %s", ErrRefreshRevoked, name)
+	case f.current != used:
+		delete(m.families, name)
+
+		return fmt.Errorf("%w: family %s", ErrRefreshReused, name)
+	}
+
+	m.families[name] = family{current: next, expires: expires}
+
+	return nil
+}
+
+// sweep removes expired families. The lock must be held by the caller.
+func (m *MemoryStore) sweep() {
+	now := time.Now()
+
+	for name, f := range m.families {
+		if now.After(f.expires) {
+			delete(m.families, name)
+		}
+	}
+}
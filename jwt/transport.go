@@ -0,0 +1,376 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/domdavis/tonic/middleware"
+	"github.com/domdavis/tonic/register"
+	"github.com/gin-gonic/gin"
+)
+
+// A TokenSource provides the bearer tokens attached to outbound requests by a
+// Transport.
+type TokenSource interface {
+	// Token returns a token and the time it expires. A zero expiry means the
+	// token is used until it is rejected.
+	Token(ctx context.Context) (string, time.Time, error)
+}
+
+// TokenSourceFunc adapts a function to a TokenSource.
+type TokenSourceFunc func(ctx context.Context) (string, time.Time, error)
+
+// Transport is an http.RoundTripper that attaches a bearer token from a
+// TokenSource to outbound requests. Tokens are cached, and fetched again
+// shortly before they expire. If a request is rejected with
+// http.StatusUnauthorized then a new token is fetched and the request is
+// retried once. Transport is safe for concurrent use.
+type Transport struct {
+	// Base RoundTripper used to send requests. If nil, http.DefaultTransport
+	// is used.
+	Base http.RoundTripper
+
+	// Source of the tokens. If nil, requests are sent without a token unless
+	// one is forwarded.
+	Source TokenSource
+
+	// Early is how long before a token expires that it is fetched again.
+	Early time.Duration
+
+	// ForwardToken sends the inbound request's bearer token, held in the
+	// request's context by Forward, in place of a token from the Source.
+	ForwardToken bool
+
+	// ForwardRequestID sends the inbound request's RequestIDHeader, held in
+	// the request's context by Forward.
+	ForwardRequestID bool
+
+	// Logger used to report outbound requests. A nil Logger will cause
+	// nothing to be reported.
+	Logger middleware.Logger
+
+	mu       sync.Mutex
+	token    string
+	expires  time.Time
+	fetching *fetch
+}
+
+// ClientCredentials is a TokenSource that fetches tokens from an OAuth 2.0
+// token endpoint, such as register.TokenEndpoint, using the client credentials
+// grant.
+type ClientCredentials struct {
+	// URL of the token endpoint.
+	URL string
+
+	// ClientID and ClientSecret used to authenticate with the token endpoint.
+	ClientID     string
+	ClientSecret string
+
+	// Scopes requested. Leave empty to be given the client's default scopes.
+	Scopes []string
+
+	// Client used to call the token endpoint. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+}
+
+// RequestIDHeader holds the ID of a request, forwarded by a Transport.
+const RequestIDHeader = "X-Request-ID"
+
+// DefaultEarly is the default time before a token expires that a Transport
+// will fetch a new one.
+const DefaultEarly = 30 * time.Second
+
+// ErrTokenFailed is returned if a token could not be fetched.
+var ErrTokenFailed = errors.New("failed to fetch token")
+
+// fetch is a token being fetched by a Transport. Requests needing a token
+// while it is being fetched wait for done to be closed and share the result.
+type fetch struct {
+	done      chan struct{}
+	token     string
+	expires   time.Time
+	err       error
+	cancelled bool
+}
+
+// forwarded holds the inbound request's token and request ID.
+type forwarded struct {
+	token     string
+	requestID string
+}
+
+type forwardKey struct{}
+
+// NewTransport returns a Transport using the given source, which fetches
+// tokens DefaultEarly, and reports requests to the given logger.
+func NewTransport(source TokenSource, logger middleware.Logger) *Transport {
+	return &Transport{Source: source, Early: DefaultEarly, Logger: logger}
+}
+
+// Forward returns a copy of the context holding the bearer token and
+// RequestIDHeader of the inbound request, for use with outbound requests sent
+// through a Transport that forwards them.
+func Forward(ctx context.Context, c *gin.Context) context.Context {
+	return context.WithValue(ctx, forwardKey{}, forwarded{
+		token:     token(c),
+		requestID: c.GetHeader(RequestIDHeader),
+	})
+}
+
+// Token calls f.
+func (f TokenSourceFunc) Token(ctx context.Context) (string, time.Time, error) {
+	return f(ctx)
+}
+
+// RoundTrip sends the request with a bearer token attached. The request is
+// retried once with a new token if it's rejected with
+// http.StatusUnauthorized, as long as the body can be sent again. Forwarded
+// tokens are never retried. The request body is always closed, even if the
+// request is never sent.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	inbound, _ := req.Context().Value(forwardKey{}).(forwarded)
+	req = req.Clone(req.Context())
+
+	if t.ForwardRequestID && inbound.requestID != "" && req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, inbound.requestID)
+	}
+
+	if t.ForwardToken && inbound.token != "" {
+		Set(req, inbound.token)
+
+		return t.send(req, start)
+	}
+
+	if t.Source == nil {
+		return t.send(req, start)
+	}
+
+	sent, err := t.attach(req, "")
+
+	if err != nil {
+		closeBody(req)
+
+		return nil, err
+	}
+
+	res, err := t.send(req, start)
+
+	if err != nil || res.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+		return res, err
+	}
+
+	_ = res.Body.Close()
+
+	if req.GetBody != nil {
+		if req.Body, err = req.GetBody(); err != nil {
+			closeBody(req)
+
+			return nil, fmt.Errorf("failed to retry request: %w", err)
+		}
+	}
+
+	t.log(middleware.WarnLevel, req, map[string]any{}, "Retrying request with a new token")
+
+	if _, err = t.attach(req, sent); err != nil {
+		closeBody(req)
+
+		return nil, err
+	}
+
+	return t.send(req, time.Now())
+}
+
+// closeBody closes the request body, if there is one. A RoundTripper must close
+// the body even when the request isn't sent.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
+
+// attach a token to the request, returning the token. If the cached token is
+// the rejected token then a new one is fetched. The lock is not held while
+// fetching, and only one token is fetched at a time, with other requests
+// waiting for, and sharing, the result. If the request that fetched the token
+// was cancelled then waiting requests will fetch a token themselves.
+func (t *Transport) attach(req *http.Request, rejected string) (string, error) {
+	for {
+		t.mu.Lock()
+
+		token, f, fetching := t.token, t.fetching, false
+		valid := token != "" && token != rejected &&
+			(t.expires.IsZero() || time.Now().Add(t.Early).Before(t.expires))
+
+		if !valid && f == nil {
+			f, fetching = &fetch{done: make(chan struct{})}, true
+			t.fetching = f
+		}
+
+		t.mu.Unlock()
+
+		switch {
+		case valid:
+			Set(req, token)
+
+			return token, nil
+		case fetching:
+			return t.fetch(req, f)
+		}
+
+		select {
+		case <-f.done:
+		case <-req.Context().Done():
+			return "", t.failed(req, req.Context().Err())
+		}
+
+		if f.err != nil && !f.cancelled {
+			return "", t.failed(req, f.err)
+		}
+	}
+}
+
+// fetch a token from the Source, caching it and sharing it with any requests
+// waiting on the fetch.
+func (t *Transport) fetch(req *http.Request, f *fetch) (string, error) {
+	f.token, f.expires, f.err = t.Source.Token(req.Context())
+	f.cancelled = req.Context().Err() != nil
+
+	t.mu.Lock()
+
+	t.fetching = nil
+
+	if f.err == nil {
+		t.token, t.expires = f.token, f.expires
+	}
+
+	t.mu.Unlock()
+	close(f.done)
+
+	if f.err != nil {
+		return "", t.failed(req, f.err)
+	}
+
+	Set(req, f.token)
+
+	return f.token, nil
+}
+
+// failed reports the failure to fetch a token for the request, returning an
+// error wrapping ErrTokenFailed.
+func (t *Transport) failed(req *http.Request, err error) error {
+	t.log(middleware.ErrorLevel, req, map[string]any{"error": err.Error()}, "Failed to fetch token")
+
+	if !errors.Is(err, ErrTokenFailed) {
+		err = fmt.Errorf("%w: %w", ErrTokenFailed, err)
+	}
+
+	return err
+}
+
+// send the request using the Base RoundTripper, reporting the outcome.
+func (t *Transport) send(req *http.Request, start time.Time) (*http.Response, error) {
+	base := t.Base
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	res, err := base.RoundTrip(req)
+	fields := map[string]any{"latency": middleware.Round(time.Since(start))}
+
+	if err != nil {
+		fields["error"] = err.Error()
+		t.log(middleware.ErrorLevel, req, fields, "Failed to send request")
+
+		//nolint:wrapcheck // RoundTrippers return errors unchanged.
+		return nil, err
+	}
+
+	fields["status"] = res.StatusCode
+
+	switch {
+	case res.StatusCode >= http.StatusInternalServerError:
+		t.log(middleware.ErrorLevel, req, fields, "Request failed")
+	case res.StatusCode >= http.StatusBadRequest:
+		t.log(middleware.WarnLevel, req, fields, "Problem with request")
+	default:
+		t.log(middleware.InfoLevel, req, fields, "Sent request")
+	}
+
+	return res, nil
+}
+
+// log the message with the request's method, URL, and request ID.
+func (t *Transport) log(level middleware.Level, req *http.Request, fields map[string]any, message string) {
+	if t.Logger == nil {
+		return
+	}
+
+	fields["method"] = req.Method
+	fields["url"] = req.URL.Redacted()
+
+	if id := req.Header.Get(RequestIDHeader); id != "" {
+		fields["request ID"] = id
+	}
+
+	t.Logger.Log(level, fields, message)
+}
+
+// Token fetches a token from the token endpoint.
+func (c *ClientCredentials) Token(ctx context.Context) (string, time.Time, error) {
+	var response register.TokenResponse
+
+	form := url.Values{"grant_type": {register.ClientCredentialsGrant}}
+
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%w: %s", ErrTokenFailed, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	client := c.Client
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%w: %s", ErrTokenFailed, err.Error())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("%w: %s returned %d", ErrTokenFailed, c.URL, res.StatusCode)
+	}
+
+	if err = json.NewDecoder(res.Body).Decode(&response); err != nil || response.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("%w: invalid response from %s", ErrTokenFailed, c.URL)
+	}
+
+	var expires time.Time
+
+	if response.ExpiresIn > 0 {
+		expires = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+
+	return response.AccessToken, expires, nil
+}
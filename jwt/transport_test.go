@@ -0,0 +1,433 @@
+package jwt_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/jwt"
+	"github.com/domdavis/tonic/middleware"
+	"github.com/domdavis/tonic/register"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleTransport() {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+	access, _ := jwt.AccessIssuer(security, nil)
+	secret, _ := register.HashSecret("s3cr3t")
+
+	router := gin.New()
+	register.TokenEndpoint(router, register.NewClients(register.Client{
+		ID:     "reporting",
+		Secret: secret,
+	}), access, nil)
+	router.GET("/api", jwt.Authenticate(security), func(c *gin.Context) {
+		c.String(http.StatusOK, "hello %s", c.GetString("sub"))
+	})
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := &http.Client{Transport: jwt.NewTransport(&jwt.ClientCredentials{
+		URL:          server.URL + register.TokenPath,
+		ClientID:     "reporting",
+		ClientSecret: "s3cr3t",
+	}, nil)}
+
+	res, err := client.Get(server.URL + "/api")
+
+	if err != nil {
+		fmt.Println(err)
+
+		return
+	}
+
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+
+	fmt.Println(res.StatusCode, string(body))
+
+	// Output:
+	// 200 hello reporting
+}
+
+// Source returns a TokenSource that counts the tokens it issues, naming each
+// token after the count. Tokens expire after the given TTL.
+func Source(ttl time.Duration) (jwt.TokenSource, *int32) {
+	var count int32
+
+	return jwt.TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+		n := atomic.AddInt32(&count, 1)
+
+		return fmt.Sprintf("token-%d", n), time.Now().Add(ttl), nil
+	}), &count
+}
+
+// Body is a request body that records when it is closed.
+type Body struct {
+	io.Reader
+	closed int32
+}
+
+// Close the body.
+func (b *Body) Close() error {
+	atomic.StoreInt32(&b.closed, 1)
+
+	return nil
+}
+
+// Closed returns true if the body has been closed.
+func (b *Body) Closed() bool {
+	return atomic.LoadInt32(&b.closed) == 1
+}
+
+// Server returns a server that only accepts the given token, or any token if
+// accepted is blank, echoing the body of accepted requests and the request ID.
+func Server(t *testing.T, accepted string) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+
+		if !strings.HasPrefix(r.Header.Get(jwt.Header), jwt.Prefix+" "+accepted) {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		_, _ = fmt.Fprintf(w, "%s %s", body, r.Header.Get(jwt.RequestIDHeader))
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func TestTransport_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Tokens are cached until they are about to expire", func(t *testing.T) {
+		t.Parallel()
+
+		source, count := Source(time.Hour)
+		transport := jwt.NewTransport(source, nil)
+		client := &http.Client{Transport: transport}
+		server, _ := Server(t, "")
+
+		for i := 0; i < 3; i++ {
+			res, err := client.Get(server.URL)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			_ = res.Body.Close()
+		}
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(count))
+
+		transport.Early = time.Hour * 2
+		res, err := client.Get(server.URL)
+
+		assert.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, int32(2), atomic.LoadInt32(count))
+	})
+
+	t.Run("Rejected requests are retried once with a new token", func(t *testing.T) {
+		t.Parallel()
+
+		source, count := Source(time.Hour)
+		instance, hook := test.NewNullLogger()
+		client := &http.Client{Transport: jwt.NewTransport(source, &middleware.Logrus{Instance: instance})}
+		server, calls := Server(t, "token-2")
+
+		res, err := client.Post(server.URL, "text/plain", strings.NewReader("body"))
+
+		assert.NoError(t, err)
+
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "body ", string(body))
+		assert.Equal(t, int32(2), atomic.LoadInt32(count))
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+		assert.Len(t, hook.Entries, 3)
+		assert.Equal(t, logrus.WarnLevel, hook.Entries[1].Level)
+
+		server, calls = Server(t, "never")
+		res, err = client.Get(server.URL)
+
+		assert.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	})
+
+	t.Run("Bodies that cannot be sent again are not retried", func(t *testing.T) {
+		t.Parallel()
+
+		source, _ := Source(time.Hour)
+		client := &http.Client{Transport: jwt.NewTransport(source, nil)}
+		server, calls := Server(t, "never")
+		req, err := http.NewRequest(http.MethodPost, server.URL, io.NopCloser(strings.NewReader("body")))
+
+		assert.NoError(t, err)
+
+		res, err := client.Do(req)
+
+		assert.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+
+	t.Run("Inbound tokens and request IDs can be forwarded", func(t *testing.T) {
+		t.Parallel()
+
+		source, count := Source(time.Hour)
+		transport := jwt.NewTransport(source, nil)
+		transport.ForwardToken = true
+		transport.ForwardRequestID = true
+		client := &http.Client{Transport: transport}
+		server, _ := Server(t, "inbound")
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.Header.Set(jwt.RequestIDHeader, "request-1")
+		jwt.Set(c.Request, "inbound")
+
+		req, err := http.NewRequestWithContext(jwt.Forward(context.Background(), c), http.MethodGet,
+			server.URL, nil)
+
+		assert.NoError(t, err)
+
+		res, err := client.Do(req)
+
+		assert.NoError(t, err)
+
+		body, _ := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, " request-1", string(body))
+		assert.Equal(t, int32(0), atomic.LoadInt32(count))
+		assert.Empty(t, req.Header.Get(jwt.RequestIDHeader))
+	})
+
+	t.Run("Failing token sources fail the request", func(t *testing.T) {
+		t.Parallel()
+
+		instance, hook := test.NewNullLogger()
+		failed := errors.New("failed")
+		client := &http.Client{Transport: jwt.NewTransport(
+			jwt.TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+				return "", time.Time{}, failed
+			}), &middleware.Logrus{Instance: instance})}
+		server, calls := Server(t, "token")
+
+		_, err := client.Get(server.URL)
+
+		assert.ErrorIs(t, err, jwt.ErrTokenFailed)
+		assert.ErrorIs(t, err, failed)
+		assert.Equal(t, int32(0), atomic.LoadInt32(calls))
+		assert.Len(t, hook.Entries, 1)
+		assert.Equal(t, logrus.ErrorLevel, hook.Entries[0].Level)
+	})
+
+	t.Run("Request bodies are closed when requests fail", func(t *testing.T) {
+		t.Parallel()
+
+		var fetched int32
+
+		server, _ := Server(t, "never")
+		transport := jwt.NewTransport(jwt.TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+			if atomic.AddInt32(&fetched, 1)%2 == 0 {
+				return "", time.Time{}, assert.AnError
+			}
+
+			return "token", time.Time{}, nil
+		}), nil)
+
+		request := func(retry *Body, err error) (*Body, *http.Request) {
+			body := &Body{Reader: strings.NewReader("body")}
+			req := httptest.NewRequest(http.MethodPost, server.URL, body)
+			req.RequestURI = ""
+			req.GetBody = func() (io.ReadCloser, error) {
+				return retry, err
+			}
+
+			return body, req
+		}
+
+		// The token source fails before the first attempt.
+		atomic.StoreInt32(&fetched, 1)
+		body, req := request(nil, nil)
+		_, err := transport.RoundTrip(req)
+
+		assert.ErrorIs(t, err, jwt.ErrTokenFailed)
+		assert.True(t, body.Closed())
+
+		// The token source fails before the retry.
+		atomic.StoreInt32(&fetched, 0)
+		retry := &Body{Reader: strings.NewReader("body")}
+		_, req = request(retry, nil)
+		_, err = transport.RoundTrip(req)
+
+		assert.ErrorIs(t, err, jwt.ErrTokenFailed)
+		assert.True(t, retry.Closed())
+
+		// The body cannot be sent again.
+		atomic.StoreInt32(&fetched, 0)
+		retry = &Body{Reader: strings.NewReader("body")}
+		_, req = request(retry, assert.AnError)
+		_, err = transport.RoundTrip(req)
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.True(t, retry.Closed())
+	})
+}
+
+func TestTransport_RoundTrip_concurrent(t *testing.T) {
+	t.Parallel()
+
+	var fetched int32
+
+	started, release := make(chan struct{}), make(chan struct{})
+	server, _ := Server(t, "token")
+	client := &http.Client{Transport: jwt.NewTransport(
+		jwt.TokenSourceFunc(func(context.Context) (string, time.Time, error) {
+			if atomic.AddInt32(&fetched, 1) == 1 {
+				close(started)
+			}
+
+			<-release
+
+			return "token", time.Now().Add(time.Hour), nil
+		}), nil)}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			res, err := client.Get(server.URL)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, http.StatusOK, res.StatusCode)
+				_ = res.Body.Close()
+			}
+		}()
+	}
+
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+
+	assert.NoError(t, err)
+
+	cancel()
+
+	done := make(chan error)
+
+	go func() {
+		res, failed := client.Do(req)
+
+		if failed == nil {
+			_ = res.Body.Close()
+		}
+
+		done <- failed
+	}()
+
+	select {
+	case err = <-done:
+		assert.ErrorIs(t, err, jwt.ErrTokenFailed)
+	case <-time.After(time.Second * 5):
+		assert.Fail(t, "cancelled request waited for the token to be fetched")
+	}
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched))
+}
+
+func TestClientCredentials_Token(t *testing.T) {
+	t.Parallel()
+
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+	access, err := jwt.AccessIssuer(security, nil)
+
+	assert.NoError(t, err)
+
+	secret, err := register.HashSecret("secret")
+
+	assert.NoError(t, err)
+
+	router := gin.New()
+	register.TokenEndpoint(router, register.NewClients(register.Client{
+		ID:     "client",
+		Secret: secret,
+		Scopes: []string{"read", "write"},
+	}), access, nil)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	t.Run("Tokens are fetched from the token endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		source := &jwt.ClientCredentials{
+			URL: server.URL + register.TokenPath, ClientID: "client", ClientSecret: "secret",
+			Scopes: []string{"read"},
+		}
+
+		token, expires, err := source.Token(context.Background())
+
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), expires, time.Second*5)
+
+		claims, err := access.Parse(token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "read", claims["scope"])
+	})
+
+	t.Run("Failures are reported", func(t *testing.T) {
+		t.Parallel()
+
+		for name, source := range map[string]*jwt.ClientCredentials{
+			"wrong secret": {URL: server.URL + register.TokenPath, ClientID: "client", ClientSecret: "wrong"},
+			"wrong scope": {
+				URL: server.URL + register.TokenPath, ClientID: "client", ClientSecret: "secret",
+				Scopes: []string{"admin"},
+			},
+			"not found":   {URL: server.URL + "/missing", ClientID: "client", ClientSecret: "secret"},
+			"unreachable": {URL: "http://localhost:0", ClientID: "client", ClientSecret: "secret"},
+			"invalid URL": {URL: "http://local host", ClientID: "client", ClientSecret: "secret"},
+		} {
+			_, _, err := source.Token(context.Background())
+
+			assert.ErrorIs(t, err, jwt.ErrTokenFailed, name)
+		}
+	})
+}
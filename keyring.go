@@ -0,0 +1,284 @@
+package tonic
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/domdavis/tonic/jwk"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// A Key is used to sign and verify tokens. Keys are identified by their ID,
+// which is set as the kid header on signed tokens.
+type Key struct {
+	// ID of the key.
+	ID string
+
+	// Method used to sign tokens with this key.
+	Method jwt.SigningMethod
+
+	// Signing key, either a []byte secret or a crypto.Signer. A Key with no
+	// Signing key can only be used to verify tokens.
+	Signing any
+
+	// Verifying key, either a []byte secret or a crypto.PublicKey.
+	Verifying any
+
+	// Retired is the time the key stopped being the active key. Retired is
+	// zero for keys that have not been retired.
+	Retired time.Time
+}
+
+// A KeySource is used to look up the key used to verify a token with the
+// given key ID.
+type KeySource interface {
+	Lookup(id string) (*Key, bool)
+}
+
+// A KeyRing holds a single active key used to sign tokens, along with any
+// retired keys that can still be used to verify tokens. Retired keys are
+// dropped once they have been retired for longer than Grace. A KeyRing is safe
+// for concurrent use.
+type KeyRing struct {
+	// Grace is the length of time a retired key is kept for. This should be
+	// at least as long as the longest TTL of any token signed with the ring.
+	// A zero Grace will keep retired keys indefinitely.
+	Grace time.Duration
+
+	mu      sync.RWMutex
+	active  *Key
+	retired []*Key
+}
+
+const keyIDLength = 12
+
+// NewSecretKey returns a Key that signs and verifies tokens with the given
+// secret using HS512. The ID of the key is derived from the secret.
+func NewSecretKey(secret string) *Key {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("kid"))
+
+	return &Key{
+		ID:        keyID(mac.Sum(nil)),
+		Method:    jwt.SigningMethodHS512,
+		Signing:   []byte(secret),
+		Verifying: []byte(secret),
+	}
+}
+
+// NewPrivateKey returns a Key that signs tokens with the given private key.
+// The signing method is chosen using MethodFor, and the ID of the key is
+// derived from the public half of the key.
+func NewPrivateKey(signer crypto.Signer) (*Key, error) {
+	key, err := NewPublicKey(signer.Public())
+
+	if err != nil {
+		return nil, err
+	}
+
+	key.Signing = signer
+
+	return key, nil
+}
+
+// NewPublicKey returns a Key that can only be used to verify tokens. The
+// signing method is chosen using MethodFor, and the ID of the key is derived
+// from the public key.
+func NewPublicKey(public crypto.PublicKey) (*Key, error) {
+	method, err := MethodFor(public)
+
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(public)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKey, err.Error())
+	}
+
+	sum := sha256.Sum256(der)
+
+	return &Key{ID: keyID(sum[:]), Method: method, Verifying: public}, nil
+}
+
+// ParseJWK returns a Key that can only be used to verify tokens, using the
+// public key held in the given JSON Web Key. The ID of the key is taken from the
+// JWK. If the JWK specifies an algorithm then it is used in place of the
+// default method for the key type.
+func ParseJWK(published jwk.Key) (*Key, error) {
+	public, err := published.Public()
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWK %s: %w", published.KeyID, err)
+	}
+
+	key, err := NewPublicKey(public)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key.ID = published.KeyID
+
+	if published.Algorithm != "" {
+		if key.Method = jwt.GetSigningMethod(published.Algorithm); key.Method == nil {
+			return nil, fmt.Errorf("%w: %s", ErrWrongAlgorithm, published.Algorithm)
+		}
+	}
+
+	return key, nil
+}
+
+// JWK returns the JSON Web Key for the verifying half of the Key. Keys using a
+// secret cannot be published and will return false.
+func (k *Key) JWK() (jwk.Key, bool) {
+	if _, ok := k.Verifying.([]byte); ok || k.Method == nil {
+		return jwk.Key{}, false
+	}
+
+	key, err := jwk.New(k.ID, k.Method.Alg(), k.Verifying)
+
+	return key, err == nil
+}
+
+// NewKeyRing returns a KeyRing with the given active key.
+func NewKeyRing(active *Key, grace time.Duration) *KeyRing {
+	return &KeyRing{Grace: grace, active: active}
+}
+
+// Active returns the key currently used for signing tokens, or nil if there
+// is no active key.
+func (k *KeyRing) Active() *Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.active
+}
+
+// Rotate the ring, retiring the currently active key and making the given key
+// the active key.
+func (k *KeyRing) Rotate(key *Key) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.rotate(key)
+}
+
+// Activate makes the given key the active key, rotating the ring, unless a key
+// with the same ID is already active. Returns true if the ring was rotated.
+func (k *KeyRing) Activate(key *Key) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.active != nil && k.active.ID == key.ID {
+		return false
+	}
+
+	k.rotate(key)
+
+	return true
+}
+
+// Retire adds a key to the ring that can only be used for verification. The
+// key's grace period starts now.
+func (k *KeyRing) Retire(key *Key) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key.Retired = time.Now()
+	k.retired = append(k.retired, key)
+}
+
+// Lookup the key with the given ID. Retired keys that are past their grace
+// period will not be returned.
+func (k *KeyRing) Lookup(id string) (*Key, bool) {
+	k.Prune()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if k.active != nil && k.active.ID == id {
+		return k.active, true
+	}
+
+	for _, key := range k.retired {
+		if key.ID == id {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+// Keys returns the active key, followed by all retired keys still in their
+// grace period.
+func (k *KeyRing) Keys() []*Key {
+	k.Prune()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := make([]*Key, 0, len(k.retired)+1)
+
+	if k.active != nil {
+		keys = append(keys, k.active)
+	}
+
+	return append(keys, k.retired...)
+}
+
+// JWKS returns the JSON Web Key Set for all keys in the ring that can be
+// published. Secrets are never published.
+func (k *KeyRing) JWKS() jwk.Set {
+	set := jwk.Set{Keys: []jwk.Key{}}
+
+	for _, key := range k.Keys() {
+		if published, ok := key.JWK(); ok {
+			set.Keys = append(set.Keys, published)
+		}
+	}
+
+	return set
+}
+
+// Prune any retired keys that have passed their grace period.
+func (k *KeyRing) Prune() {
+	if k.Grace <= 0 {
+		return
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	retained := k.retired[:0]
+
+	for _, key := range k.retired {
+		if time.Since(key.Retired) < k.Grace {
+			retained = append(retained, key)
+		}
+	}
+
+	k.retired = retained
+}
+
+// rotate retires the active key and makes the given key the active key. The
+// caller must hold the lock.
+func (k *KeyRing) rotate(key *Key) {
+	if k.active != nil {
+		k.active.Retired = time.Now()
+		k.retired = append(k.retired, k.active)
+	}
+
+	k.active = key
+}
+
+func keyID(sum []byte) string {
+	return base64.RawURLEncoding.EncodeToString(sum[:keyIDLength])
+}
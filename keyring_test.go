@@ -0,0 +1,232 @@
+package tonic_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/jwk"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleKeyRing_Rotate() {
+	old := tonic.NewSecretKey("old secret")
+	ring := tonic.NewKeyRing(old, time.Hour)
+	s := &tonic.Signatory{TTL: time.Hour, Keys: ring}
+
+	token, _ := s.Sign(&gin.Context{})
+
+	ring.Rotate(tonic.NewSecretKey("new secret"))
+
+	// Tokens signed with the old key are still valid during the grace period.
+	fmt.Println(s.Validate(&gin.Context{}, token))
+	fmt.Println(len(ring.Keys()))
+
+	// Output:
+	// <nil>
+	// 2
+}
+
+func TestKeyRing_Lookup(t *testing.T) {
+	t.Run("Active and retired keys can be found", func(t *testing.T) {
+		t.Parallel()
+
+		active := tonic.NewSecretKey("active")
+		retired := tonic.NewSecretKey("retired")
+		ring := tonic.NewKeyRing(active, time.Hour)
+		ring.Retire(retired)
+
+		key, ok := ring.Lookup(active.ID)
+
+		assert.True(t, ok)
+		assert.Equal(t, active, key)
+
+		key, ok = ring.Lookup(retired.ID)
+
+		assert.True(t, ok)
+		assert.Equal(t, retired, key)
+	})
+
+	t.Run("Unknown keys are not found", func(t *testing.T) {
+		t.Parallel()
+
+		ring := tonic.NewKeyRing(tonic.NewSecretKey("active"), time.Hour)
+
+		_, ok := ring.Lookup("unknown")
+
+		assert.False(t, ok)
+	})
+
+	t.Run("Keys past their grace period are dropped", func(t *testing.T) {
+		t.Parallel()
+
+		retired := tonic.NewSecretKey("retired")
+		ring := tonic.NewKeyRing(tonic.NewSecretKey("active"), time.Millisecond)
+		ring.Retire(retired)
+
+		time.Sleep(time.Millisecond * 2)
+
+		_, ok := ring.Lookup(retired.ID)
+
+		assert.False(t, ok)
+		assert.Len(t, ring.Keys(), 1)
+	})
+
+	t.Run("A zero grace period keeps retired keys", func(t *testing.T) {
+		t.Parallel()
+
+		retired := tonic.NewSecretKey("retired")
+		ring := tonic.NewKeyRing(tonic.NewSecretKey("active"), 0)
+		ring.Retire(retired)
+
+		_, ok := ring.Lookup(retired.ID)
+
+		assert.True(t, ok)
+	})
+}
+
+func TestNewSecretKey(t *testing.T) {
+	t.Run("Key IDs are stable and distinct", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Equal(t, tonic.NewSecretKey("a").ID, tonic.NewSecretKey("a").ID)
+		assert.NotEqual(t, tonic.NewSecretKey("a").ID, tonic.NewSecretKey("b").ID)
+	})
+}
+
+func TestNewPublicKey(t *testing.T) {
+	t.Run("Unsupported keys will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.NewPublicKey("key")
+
+		assert.ErrorIs(t, err, tonic.ErrUnsupportedKey)
+	})
+}
+
+func TestSignatory_Keys(t *testing.T) {
+	t.Run("Tokens signed with a retired secret are valid", func(t *testing.T) {
+		t.Parallel()
+
+		old, err := tonic.NewSignatory(config.Security{Secret: "old", SessionTTL: time.Hour})
+
+		assert.NoError(t, err)
+
+		token, err := old.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+
+		rotated, err := tonic.NewSignatory(config.Security{
+			Secret: "new", RetiredSecrets: "older, old", SessionTTL: time.Hour,
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, rotated.Validate(&gin.Context{}, token))
+
+		unrotated, err := tonic.NewSignatory(config.Security{Secret: "new", SessionTTL: time.Hour})
+
+		assert.NoError(t, err)
+		assert.Error(t, unrotated.Validate(&gin.Context{}, token))
+	})
+
+	t.Run("Tokens signed with a retired key are valid", func(t *testing.T) {
+		t.Parallel()
+
+		old, err := tonic.NewSignatory(config.Security{
+			PrivateKey: "testdata/rsa.key", SessionTTL: time.Hour,
+		})
+
+		assert.NoError(t, err)
+
+		token, err := old.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+
+		rotated, err := tonic.NewSignatory(config.Security{
+			PrivateKey: "testdata/ecdsa.key", RetiredKeys: "testdata/rsa.pub",
+			SessionTTL: time.Hour,
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, rotated.Validate(&gin.Context{}, token))
+	})
+
+	t.Run("Invalid retired keys will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.NewSignatory(config.Security{RetiredKeys: "testdata/missing.pub"})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Tokens without a key ID are validated with the active key", func(t *testing.T) {
+		t.Parallel()
+
+		legacy := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		token, err := legacy.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+
+		s, err := tonic.NewSignatory(config.Security{Secret: "secret"})
+
+		assert.NoError(t, err)
+		assert.NoError(t, s.Validate(&gin.Context{}, token))
+	})
+
+	t.Run("A KeyRing with no active key cannot sign", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{TTL: time.Hour, Keys: &tonic.KeyRing{}}
+
+		_, err := s.Sign(&gin.Context{})
+
+		assert.ErrorIs(t, err, tonic.ErrMissingKey)
+	})
+}
+
+func TestParseJWK(t *testing.T) {
+	t.Run("The JWK algorithm is used if set", func(t *testing.T) {
+		t.Parallel()
+
+		public, err := tonic.LoadPublicKey("testdata/rsa.pub")
+
+		assert.NoError(t, err)
+
+		published, err := jwk.New("id", "RS512", public)
+
+		assert.NoError(t, err)
+
+		key, err := tonic.ParseJWK(published)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "id", key.ID)
+		assert.Equal(t, "RS512", key.Method.Alg())
+	})
+
+	t.Run("Unknown algorithms will error", func(t *testing.T) {
+		t.Parallel()
+
+		public, err := tonic.LoadPublicKey("testdata/rsa.pub")
+
+		assert.NoError(t, err)
+
+		published, err := jwk.New("id", "XX999", public)
+
+		assert.NoError(t, err)
+
+		_, err = tonic.ParseJWK(published)
+
+		assert.ErrorIs(t, err, tonic.ErrWrongAlgorithm)
+	})
+
+	t.Run("Invalid JWKs will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.ParseJWK(jwk.Key{KeyType: "oct"})
+
+		assert.ErrorIs(t, err, jwk.ErrUnsupportedKey)
+	})
+}
@@ -0,0 +1,131 @@
+package tonic
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Key loading errors.
+var (
+	ErrInvalidPEM       = errors.New("invalid PEM data")
+	ErrUnsupportedKey   = errors.New("unsupported key type")
+	ErrMissingKey       = errors.New("missing key")
+	ErrUnsupportedCurve = errors.New("unsupported elliptic curve")
+	ErrKeyMismatch      = errors.New("public key does not match private key")
+)
+
+// LoadPrivateKey will load a PEM encoded RSA, ECDSA, or Ed25519 private key
+// from the given path. PKCS #1, PKCS #8, and SEC 1 encodings are supported.
+func LoadPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return signer(key)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s is not a private key", ErrInvalidPEM, path)
+}
+
+// LoadPublicKey will load a PEM encoded RSA, ECDSA, or Ed25519 public key from
+// the given path. PKIX and PKCS #1 encodings are supported, as are
+// certificates, in which case the certificate's public key is returned.
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		return cert.PublicKey, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s is not a public key", ErrInvalidPEM, path)
+}
+
+// MethodFor returns the signing method used for the given key. RSA keys use
+// RS256, ECDSA keys use ES256, ES384, or ES512 depending on the curve, and
+// Ed25519 keys use EdDSA. Both private and public keys are accepted.
+func MethodFor(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	if s, ok := key.(crypto.Signer); ok {
+		key = s.Public()
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve.Params().BitSize {
+		case jwt.SigningMethodES256.CurveBits:
+			return jwt.SigningMethodES256, nil
+		case jwt.SigningMethodES384.CurveBits:
+			return jwt.SigningMethodES384, nil
+		case jwt.SigningMethodES512.CurveBits:
+			return jwt.SigningMethodES512, nil
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedCurve, k.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	b, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(b)
+
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPEM, path)
+	}
+
+	return block, nil
+}
+
+func signer(key any) (crypto.Signer, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+}
@@ -0,0 +1,149 @@
+package tonic_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"github.com/domdavis/tonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleMethodFor() {
+	for _, path := range []string{
+		"testdata/rsa.key", "testdata/ecdsa.key", "testdata/ed25519.key",
+	} {
+		key, err := tonic.LoadPrivateKey(path)
+
+		if err != nil {
+			fmt.Println(err)
+		}
+
+		method, err := tonic.MethodFor(key)
+
+		if err != nil {
+			fmt.Println(err)
+		}
+
+		fmt.Println(method.Alg())
+	}
+
+	// Output:
+	// RS256
+	// ES256
+	// EdDSA
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	t.Run("Private keys can be loaded", func(t *testing.T) {
+		t.Parallel()
+
+		for _, path := range []string{
+			"testdata/rsa.key", "testdata/ecdsa.key", "testdata/ed25519.key",
+		} {
+			key, err := tonic.LoadPrivateKey(path)
+
+			assert.NoError(t, err)
+			assert.NotNil(t, key)
+		}
+	})
+
+	t.Run("A missing file will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.LoadPrivateKey("testdata/missing.key")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("A non PEM file will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.LoadPrivateKey("testdata/static/index.html")
+
+		assert.ErrorIs(t, err, tonic.ErrInvalidPEM)
+	})
+
+	t.Run("A public key is not a private key", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.LoadPrivateKey("testdata/rsa.pub")
+
+		assert.ErrorIs(t, err, tonic.ErrInvalidPEM)
+	})
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	t.Run("Public keys can be loaded", func(t *testing.T) {
+		t.Parallel()
+
+		for _, path := range []string{
+			"testdata/rsa.pub", "testdata/ecdsa.pub", "testdata/ed25519.pub",
+			"testdata/localhost.crt",
+		} {
+			key, err := tonic.LoadPublicKey(path)
+
+			assert.NoError(t, err)
+			assert.NotNil(t, key)
+		}
+	})
+
+	t.Run("A missing file will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.LoadPublicKey("testdata/missing.pub")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("A private key is not a public key", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.LoadPublicKey("testdata/rsa.key")
+
+		assert.ErrorIs(t, err, tonic.ErrInvalidPEM)
+	})
+}
+
+func TestMethodFor(t *testing.T) {
+	t.Run("Unsupported keys will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.MethodFor("key")
+
+		assert.ErrorIs(t, err, tonic.ErrUnsupportedKey)
+	})
+
+	t.Run("Unsupported curves will error", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+
+		assert.NoError(t, err)
+
+		_, err = tonic.MethodFor(key)
+
+		assert.ErrorIs(t, err, tonic.ErrUnsupportedCurve)
+	})
+
+	t.Run("All supported curves are handled", func(t *testing.T) {
+		t.Parallel()
+
+		for curve, alg := range map[elliptic.Curve]string{
+			elliptic.P256(): "ES256",
+			elliptic.P384(): "ES384",
+			elliptic.P521(): "ES512",
+		} {
+			key, err := ecdsa.GenerateKey(curve, rand.Reader)
+
+			assert.NoError(t, err)
+
+			method, err := tonic.MethodFor(&key.PublicKey)
+
+			assert.NoError(t, err)
+			assert.Equal(t, alg, method.Alg())
+		}
+	})
+}
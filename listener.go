@@ -2,8 +2,10 @@ package tonic
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/domdavis/tonic/config"
 	"github.com/sirupsen/logrus"
@@ -11,12 +13,20 @@ import (
 
 // Listen on the given port, using TLS if this is configured in the settings.
 // Listen is non-blocking, the returned Done channel can be used to block.
-// Call Server.Cancel to cancel Listening.
+// Call Server.Cancel to cancel Listening. If the server cannot be created then
+// the returned server is nil and the error is sent on the Done channel, in the
+// same way as an invalid certificate or key.
 func Listen(settings config.Server, router http.Handler) (*http.Server, <-chan error) {
 	var protocol string
 
-	done := make(chan error)
-	server := NewServer(settings, router)
+	done := make(chan error, 1)
+	server, err := NewServer(settings, router)
+
+	if err != nil {
+		done <- err
+
+		return server, done
+	}
 
 	if settings.TLS() {
 		protocol = "HTTPS"
@@ -40,8 +50,10 @@ func Listen(settings config.Server, router http.Handler) (*http.Server, <-chan e
 	return server, done
 }
 
-// NewServer will return a configured http.Server for the given router.
-func NewServer(settings config.Server, router http.Handler) *http.Server {
+// NewServer will return a configured http.Server for the given router. An error
+// is returned if the client certificate settings are invalid, or the ClientCA
+// cannot be loaded.
+func NewServer(settings config.Server, router http.Handler) (*http.Server, error) {
 	addressFormat := ":%d"
 
 	if settings.Development {
@@ -69,9 +81,49 @@ func NewServer(settings config.Server, router http.Handler) *http.Server {
 			},
 		}
 
+		if err := clientAuth(settings, server.TLSConfig); err != nil {
+			return nil, err
+		}
+
 		server.TLSNextProto = make(
 			map[string]func(*http.Server, *tls.Conn, http.Handler))
 	}
 
-	return server
+	return server, nil
+}
+
+// clientAuth applies the client certificate settings to the TLS config.
+func clientAuth(settings config.Server, tlsConfig *tls.Config) error {
+	mode, err := settings.ClientAuthType()
+
+	if err != nil {
+		return fmt.Errorf("failed to configure client certificates: %w", err)
+	}
+
+	if settings.ClientCA != "" {
+		if tlsConfig.ClientCAs, err = clientCAs(settings.ClientCA); err != nil {
+			return err
+		}
+	}
+
+	tlsConfig.ClientAuth = mode
+
+	return nil
+}
+
+// clientCAs loads the PEM encoded certificates in the file at path.
+func clientCAs(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA %s: %w", path, err)
+	}
+
+	pool := x509.NewCertPool()
+
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("%w: no certificates in %s", config.ErrInvalidClientAuth, path)
+	}
+
+	return pool, nil
 }
@@ -2,6 +2,7 @@ package tonic_test
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"net/http"
@@ -81,6 +82,90 @@ func TestListen(t *testing.T) {
 
 		_ = s.Close()
 	})
+
+	t.Run("Listen works for mTLS", func(t *testing.T) {
+		port := Port()
+		settings := config.Server{
+			Port:        port,
+			Key:         "testdata/localhost.key",
+			Certificate: "testdata/localhost.crt",
+			ClientCA:    "testdata/ca.crt",
+			Development: true,
+		}
+
+		r, err := tonic.New(settings)
+
+		assert.NoError(t, err)
+
+		s, _ := tonic.Listen(settings, r)
+
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(ping, "https", port), nil)
+
+		assert.NoError(t, err)
+
+		certificate, err := tls.LoadX509KeyPair("testdata/client.crt", "testdata/client.key")
+
+		assert.NoError(t, err)
+
+		code, err := connect(req, certificate)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, code)
+
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+
+		_, err = client.Do(req)
+
+		assert.Error(t, err)
+
+		_ = s.Close()
+	})
+}
+
+func TestNewServer(t *testing.T) {
+	t.Parallel()
+
+	for name, settings := range map[string]config.Server{
+		"invalid client CA":    {ClientCA: "testdata/localhost.key"},
+		"missing client CA":    {ClientCA: "testdata/missing.crt"},
+		"invalid client auth":  {ClientCA: "testdata/ca.crt", ClientAuth: "always"},
+		"client auth needs CA": {ClientAuth: "verify"},
+	} {
+		settings := settings
+
+		t.Run(name+" will fail", func(t *testing.T) {
+			t.Parallel()
+
+			settings.Key = "testdata/localhost.key"
+			settings.Certificate = "testdata/localhost.crt"
+			settings.Port = Port()
+
+			server, err := tonic.NewServer(settings, http.NotFoundHandler())
+
+			assert.Error(t, err)
+			assert.Nil(t, server)
+
+			_, done := tonic.Listen(settings, http.NotFoundHandler())
+
+			assert.Error(t, <-done)
+		})
+	}
+
+	t.Run("Client certificates are verified against the client CA", func(t *testing.T) {
+		t.Parallel()
+
+		server, err := tonic.NewServer(config.Server{
+			Key:         "testdata/localhost.key",
+			Certificate: "testdata/localhost.crt",
+			ClientCA:    "testdata/ca.crt",
+		}, http.NotFoundHandler())
+
+		assert.NoError(t, err)
+		assert.Equal(t, tls.RequireAndVerifyClientCert, server.TLSConfig.ClientAuth)
+		assert.False(t, server.TLSConfig.ClientCAs.Equal(x509.NewCertPool()))
+	})
 }
 
 // Port returns a usable, free port from the OS. Port will return 0 on error.
@@ -93,13 +178,16 @@ func Port() int {
 	return listener.Addr().(*net.TCPAddr).Port
 }
 
-func connect(req *http.Request) (int, error) {
+func connect(req *http.Request, certificates ...tls.Certificate) (int, error) {
 	var (
 		err error
 		res *http.Response
 	)
 
-	tr := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	tr := &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       certificates,
+	}}
 	client := &http.Client{Transport: tr}
 
 	for i := 0; i <= 5; i++ {
@@ -7,10 +7,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// Reporter is used to report on requests.
+// Reporter is used to report on requests. Headers maps request headers, and
+// Keys maps context keys, to the fields they are reported under.
 type Reporter struct {
 	Logger  Logger
 	Headers map[string]string
+	Keys    map[string]string
 	skip    map[string]struct{}
 }
 
@@ -30,6 +32,16 @@ const (
 	InfoLevel
 )
 
+// Context keys reported by default. These are set by the auth and apikey
+// packages, which hold them here so they can be reported.
+const (
+	// MethodKey holds the name of the method that authenticated the request.
+	MethodKey = "tonic.auth.method"
+
+	// APIKeyKey holds the name of the API key that authenticated the request.
+	APIKeyKey = "tonic.apikey"
+)
+
 // NewReporter returns a new Reporter for use with Tonic. It will use the given
 // Logger to log the reports. A nil logger will cause nothing to be reported.
 func NewReporter(logger Logger) *Reporter {
@@ -40,6 +52,10 @@ func NewReporter(logger Logger) *Reporter {
 			"X-Forwarded-Proto": "forwarded protocol",
 			"X-Forwarded-Port":  "forwarded port",
 		},
+		Keys: map[string]string{
+			MethodKey: "auth method",
+			APIKeyKey: "API key",
+		},
 	}
 }
 
@@ -102,6 +118,12 @@ func (r *Reporter) Embellish(c *gin.Context, fields map[string]any) map[string]a
 		}
 	}
 
+	for key, field := range r.Keys {
+		if v, ok := c.Get(key); ok {
+			fields[field] = v
+		}
+	}
+
 	if c.ClientIP() != "" {
 		fields["client IP"] = c.ClientIP()
 	}
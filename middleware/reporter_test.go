@@ -138,6 +138,10 @@ func TestLogger_Embellish(t *testing.T) {
 			assert.NotContains(t, hook.LastEntry().Data, entry)
 		}
 
+		for _, entry := range logger.Keys {
+			assert.NotContains(t, hook.LastEntry().Data, entry)
+		}
+
 		assert.NotContains(t, hook.LastEntry().Data, "client IP")
 		assert.NotContains(t, hook.LastEntry().Data, "referer")
 		assert.NotContains(t, hook.LastEntry().Data, "errors")
@@ -159,12 +163,20 @@ func TestLogger_Embellish(t *testing.T) {
 			c.Request.Header[k] = []string{v}
 		}
 
+		for k, v := range logger.Keys {
+			c.Set(k, v)
+		}
+
 		logger.Log(c)
 
 		for _, entry := range logger.Headers {
 			assert.Contains(t, hook.LastEntry().Data, entry)
 		}
 
+		for _, entry := range logger.Keys {
+			assert.Contains(t, hook.LastEntry().Data, entry)
+		}
+
 		assert.Contains(t, hook.LastEntry().Data, "client IP")
 		assert.Contains(t, hook.LastEntry().Data, "referer")
 		assert.Contains(t, hook.LastEntry().Data, "errors")
@@ -0,0 +1,4 @@
+// Package mtls handles authentication with client certificates verified by a
+// server using mutual TLS. See config.Server for the client certificate
+// settings.
+package mtls
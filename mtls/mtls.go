@@ -0,0 +1,119 @@
+package mtls
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/domdavis/tonic"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Authenticator authenticates requests using the client certificate verified
+// during the TLS handshake. An Authenticator can be used with an auth.Chain,
+// or using the Authenticate middleware.
+type Authenticator struct{}
+
+// Method is the authentication method used by an Authenticator.
+const Method = "mtls"
+
+// Claims set from the client certificate.
+const (
+	// CommonNameClaim holds the common name of the certificate subject.
+	CommonNameClaim = "cn"
+
+	// URIsClaim holds the URI subject alternative names, such as SPIFFE IDs.
+	URIsClaim = "uris"
+
+	// FingerprintClaim holds the hex encoded SHA-256 fingerprint of the
+	// certificate.
+	FingerprintClaim = "fingerprint"
+)
+
+// ErrUnverifiedCertificate is returned if the client sent a certificate that
+// wasn't verified by the server.
+var ErrUnverifiedCertificate = errors.New("unverified client certificate")
+
+// NewAuthenticator returns an Authenticator for client certificates.
+func NewAuthenticator() *Authenticator {
+	return &Authenticator{}
+}
+
+// Method returns the authentication method used by the Authenticator.
+func (a *Authenticator) Method() string {
+	return Method
+}
+
+// Authenticate the request using the verified client certificate. The claims
+// are set on the context, and as the principal under tonic.PrincipalKey. The
+// subject is the first URI subject alternative name, or the common name if the
+// certificate has no URIs. tonic.ErrMissingToken is returned if there is no
+// client certificate, and ErrUnverifiedCertificate if the server is set up to
+// accept certificates without verifying them.
+func (a *Authenticator) Authenticate(c *gin.Context) error {
+	if c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+		return fmt.Errorf("failed to authenticate client certificate: %w", tonic.ErrMissingToken)
+	}
+
+	if len(c.Request.TLS.VerifiedChains) == 0 {
+		return fmt.Errorf("failed to authenticate client certificate: %w", ErrUnverifiedCertificate)
+	}
+
+	claims := Claims(c.Request.TLS.PeerCertificates[0])
+
+	for k, v := range claims {
+		c.Set(k, v)
+	}
+
+	c.Set(tonic.PrincipalKey, claims)
+
+	return nil
+}
+
+// Claims returns the claims for the given certificate.
+func Claims(certificate *x509.Certificate) jwt.MapClaims {
+	fingerprint := sha256.Sum256(certificate.Raw)
+	uris := make([]string, 0, len(certificate.URIs))
+
+	for _, uri := range certificate.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	claims := jwt.MapClaims{
+		CommonNameClaim:    certificate.Subject.CommonName,
+		URIsClaim:          uris,
+		FingerprintClaim:   hex.EncodeToString(fingerprint[:]),
+		tonic.SubjectClaim: certificate.Subject.CommonName,
+	}
+
+	if len(uris) > 0 {
+		claims[tonic.SubjectClaim] = uris[0]
+	}
+
+	return claims
+}
+
+// Authenticate returns middleware that authenticates requests using the
+// verified client certificate. Failure to authenticate will abort the
+// middleware chain and return http.StatusUnauthorized, with the reason passed
+// to c.Error.
+func Authenticate() gin.HandlerFunc {
+	a := NewAuthenticator()
+
+	return func(c *gin.Context) {
+		if err := a.Authenticate(c); err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(err)
+			c.Abort()
+			c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,126 @@
+package mtls_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/mtls"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleAuthenticate() {
+	certificate := Certificate("../testdata/client.crt")
+
+	router := gin.New()
+	router.GET("/service", mtls.Authenticate(), tonic.Require(), func(c *gin.Context) {
+		c.String(http.StatusOK, "hello %s", tonic.Get[string](c, tonic.SubjectClaim))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "https://localhost/service", nil)
+	req.TLS.PeerCertificates = []*x509.Certificate{certificate}
+	req.TLS.VerifiedChains = [][]*x509.Certificate{{certificate}}
+	router.ServeHTTP(w, req)
+
+	fmt.Println(w.Code, w.Body.String())
+
+	// Output:
+	// 200 hello spiffe://example.org/client
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	t.Parallel()
+
+	certificate := Certificate("../testdata/client.crt")
+
+	t.Run("Verified certificates set the claims", func(t *testing.T) {
+		t.Parallel()
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "https://localhost/", nil)
+		c.Request.TLS.PeerCertificates = []*x509.Certificate{certificate}
+		c.Request.TLS.VerifiedChains = [][]*x509.Certificate{{certificate}}
+
+		err := mtls.NewAuthenticator().Authenticate(c)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "client", tonic.Get[string](c, mtls.CommonNameClaim))
+		assert.Equal(t, []string{"spiffe://example.org/client"}, tonic.Get[[]string](c, mtls.URIsClaim))
+		assert.Equal(t, "6066e5fe639573ad026179b2d98e2a61808c6e3567eeba138a9f4ee23b049613",
+			tonic.Get[string](c, mtls.FingerprintClaim))
+
+		principal, ok := tonic.Principal[jwt.MapClaims](c)
+
+		assert.True(t, ok)
+		assert.Equal(t, "spiffe://example.org/client", principal[tonic.SubjectClaim])
+	})
+
+	t.Run("Missing certificates are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+
+		err := mtls.NewAuthenticator().Authenticate(c)
+
+		assert.ErrorIs(t, err, tonic.ErrMissingToken)
+
+		c.Request = httptest.NewRequest(http.MethodGet, "https://localhost/", nil)
+
+		err = mtls.NewAuthenticator().Authenticate(c)
+
+		assert.ErrorIs(t, err, tonic.ErrMissingToken)
+	})
+
+	t.Run("Unverified certificates are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "https://localhost/", nil)
+		c.Request.TLS.PeerCertificates = []*x509.Certificate{certificate}
+
+		mtls.Authenticate()(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Len(t, c.Errors, 1)
+		assert.ErrorIs(t, c.Errors[0], mtls.ErrUnverifiedCertificate)
+		assert.Empty(t, tonic.Get[string](c, tonic.SubjectClaim))
+	})
+}
+
+func ExampleClaims() {
+	claims := mtls.Claims(Certificate("../testdata/client.crt"))
+
+	fmt.Println(claims[mtls.CommonNameClaim], claims[mtls.URIsClaim])
+
+	// Output:
+	// client [spiffe://example.org/client]
+}
+
+// Certificate returns the PEM encoded certificate at path, panicking if it
+// cannot be read.
+func Certificate(path string) *x509.Certificate {
+	b, err := os.ReadFile(path)
+
+	if err != nil {
+		panic(err)
+	}
+
+	block, _ := pem.Decode(b)
+	certificate, err := x509.ParseCertificate(block.Bytes)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return certificate
+}
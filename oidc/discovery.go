@@ -0,0 +1,112 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/domdavis/tonic/jwt"
+)
+
+// Discovery is the subset of the OpenID Provider Metadata used to log in.
+type Discovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	Algorithms            []string `json:"id_token_signing_alg_values_supported,omitempty"`
+}
+
+// DiscoveryPath is the well known path, relative to the issuer, that the
+// discovery document is served from.
+const DiscoveryPath = "/.well-known/openid-configuration"
+
+// ErrDiscoveryFailed is returned if the discovery document could not be
+// fetched, or is invalid.
+var ErrDiscoveryFailed = errors.New("failed to fetch discovery document")
+
+const discoveryTimeout = 10 * time.Second
+
+// Discover returns the provider's discovery document, fetching it if it hasn't
+// been fetched, or is older than the TTL. If a refresh fails the previously
+// fetched document remains in use. The JWKS used to validate ID tokens is
+// updated to match the document. The lock is not held while the document is
+// fetched, and other requests keep using the previous document while it is
+// being refreshed.
+func (p *Provider) Discover(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+
+	if p.discovery != nil && time.Since(p.fetched) < p.TTL {
+		defer p.mu.Unlock()
+
+		return p.discovery, nil
+	}
+
+	p.fetched = time.Now()
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+
+	discovery, err := p.fetch(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case err != nil && p.discovery != nil:
+		return p.discovery, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if p.keys == nil || p.keys.URL != discovery.JWKSURI {
+		p.keys = jwt.NewJWKS(discovery.JWKSURI)
+		p.keys.Client = p.client()
+	}
+
+	p.discovery = discovery
+
+	return discovery, nil
+}
+
+func (p *Provider) fetch(ctx context.Context) (*Discovery, error) {
+	var discovery Discovery
+
+	url := strings.TrimSuffix(p.Issuer, "/") + DiscoveryPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDiscoveryFailed, err.Error())
+	}
+
+	res, err := p.client().Do(req)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDiscoveryFailed, err.Error())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s returned %d", ErrDiscoveryFailed, url, res.StatusCode)
+	}
+
+	if err = json.NewDecoder(res.Body).Decode(&discovery); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDiscoveryFailed, err.Error())
+	}
+
+	switch {
+	case discovery.Issuer != p.Issuer:
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrDiscoveryFailed,
+			discovery.Issuer, p.Issuer)
+	case discovery.AuthorizationEndpoint == "", discovery.TokenEndpoint == "", discovery.JWKSURI == "":
+		return nil, fmt.Errorf("%w: missing endpoints", ErrDiscoveryFailed)
+	}
+
+	return &discovery, nil
+}
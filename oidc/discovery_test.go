@@ -0,0 +1,152 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvider_Discover(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Discovery documents are cached", func(t *testing.T) {
+		t.Parallel()
+
+		p := NewProvider()
+		t.Cleanup(p.Close)
+
+		provider := oidc.NewProvider(p.URL, clientID, clientSecret, "http://localhost/callback")
+
+		for i := 0; i < 3; i++ {
+			discovery, err := provider.Discover(context.Background())
+
+			assert.NoError(t, err)
+			assert.Equal(t, p.URL+"/token", discovery.TokenEndpoint)
+		}
+
+		assert.Equal(t, 1, p.Discoveries)
+	})
+
+	t.Run("The cached document is used if a refresh fails", func(t *testing.T) {
+		t.Parallel()
+
+		p := NewProvider()
+		provider := oidc.NewProvider(p.URL, clientID, clientSecret, "http://localhost/callback")
+		provider.TTL = time.Nanosecond
+
+		_, err := provider.Discover(context.Background())
+
+		assert.NoError(t, err)
+
+		p.Close()
+
+		discovery, err := provider.Discover(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, p.URL, discovery.Issuer)
+	})
+
+	t.Run("Refreshes don't block requests using the cached document", func(t *testing.T) {
+		t.Parallel()
+
+		var calls int32
+
+		entered, release := make(chan struct{}), make(chan struct{})
+		router := gin.New()
+		server := httptest.NewServer(router)
+		router.GET(oidc.DiscoveryPath, func(c *gin.Context) {
+			if atomic.AddInt32(&calls, 1) == 2 {
+				close(entered)
+				<-release
+			}
+
+			c.JSON(http.StatusOK, oidc.Discovery{
+				Issuer:                server.URL,
+				AuthorizationEndpoint: server.URL + "/authorize",
+				TokenEndpoint:         server.URL + "/token",
+				JWKSURI:               server.URL + "/jwks",
+			})
+		})
+
+		t.Cleanup(server.Close)
+
+		provider := oidc.NewProvider(server.URL, clientID, clientSecret, "http://localhost/callback")
+		provider.TTL = time.Millisecond * 100
+
+		_, err := provider.Discover(context.Background())
+
+		assert.NoError(t, err)
+
+		time.Sleep(provider.TTL)
+
+		refreshed := make(chan error)
+
+		go func() {
+			_, failed := provider.Discover(context.Background())
+			refreshed <- failed
+		}()
+
+		<-entered
+
+		done := make(chan error)
+
+		go func() {
+			_, failed := provider.Discover(context.Background())
+			done <- failed
+		}()
+
+		select {
+		case err = <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second * 5):
+			assert.Fail(t, "request waited for the refresh")
+		}
+
+		close(release)
+
+		assert.NoError(t, <-refreshed)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("Invalid discovery documents are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		p := NewProvider()
+		t.Cleanup(p.Close)
+
+		for name, issuer := range map[string]string{
+			"wrong issuer": p.URL + "/",
+			"not found":    p.URL + "/missing",
+			"unreachable":  "http://localhost:0",
+		} {
+			provider := oidc.NewProvider(issuer, clientID, clientSecret, "http://localhost/callback")
+
+			_, err := provider.Discover(context.Background())
+
+			assert.ErrorIs(t, err, oidc.ErrDiscoveryFailed, name)
+		}
+	})
+
+	t.Run("Logins fail if discovery fails", func(t *testing.T) {
+		t.Parallel()
+
+		provider := httptest.NewServer(http.NotFoundHandler())
+		s := NewService(provider.URL, clientSecret)
+
+		t.Cleanup(provider.Close)
+		t.Cleanup(s.Close)
+
+		res, err := s.Browser.Get(s.URL + "/page")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+		assert.ErrorIs(t, s.Errors[0], oidc.ErrDiscoveryFailed)
+	})
+}
@@ -0,0 +1,5 @@
+// Package oidc handles browser login through an OpenID Connect identity
+// provider, using the authorization code flow with PKCE. A successful login
+// drops a session cookie, so requests can be authenticated using
+// cookie.Authenticate.
+package oidc
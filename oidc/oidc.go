@@ -0,0 +1,501 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/cookie"
+	"github.com/domdavis/tonic/jwt"
+	"github.com/gin-gonic/gin"
+)
+
+// Provider is an OpenID Connect identity provider that users log in through.
+// The provider's discovery document and JWKS are fetched on first use and
+// cached. Provider is safe for concurrent use.
+type Provider struct {
+	// Issuer of the provider. The discovery document is fetched from the
+	// DiscoveryPath below the issuer, and ID tokens must be issued by it.
+	Issuer string
+
+	// ClientID registered with the provider.
+	ClientID string
+
+	// ClientSecret registered with the provider. Leave blank for public
+	// clients, which rely on PKCE alone.
+	ClientSecret string
+
+	// RedirectURL registered with the provider. The callback route is
+	// registered on the path of the URL.
+	RedirectURL string
+
+	// Scopes requested from the provider, which must include openid.
+	Scopes []string
+
+	// Claims maps the ID token claims to the session claims passed to
+	// cookie.Drop. Only the mapped claims are kept.
+	Claims map[string]string
+
+	// Landing is the URL users are sent to after logging in if the login
+	// request had no next parameter.
+	Landing string
+
+	// Client used to talk to the provider.
+	Client *http.Client
+
+	// TTL for the cached discovery document.
+	TTL time.Duration
+
+	mu        sync.Mutex
+	discovery *Discovery
+	fetched   time.Time
+	keys      *jwt.JWKS
+}
+
+// Names used for the state cookie and its token type.
+const (
+	StateName = "GinAndTonicOIDC"
+	StateType = "oidc+jwt"
+)
+
+// StateTTL is the length of time a user has to log in with the provider.
+const StateTTL = time.Minute * 10
+
+// DefaultTTL for the cached discovery document.
+const DefaultTTL = time.Hour
+
+// OIDC errors.
+var (
+	ErrProvider       = errors.New("provider returned an error")
+	ErrInvalidState   = errors.New("invalid login state")
+	ErrExchangeFailed = errors.New("failed to exchange code")
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+const (
+	stateClaim    = "state"
+	nonceClaim    = "nonce"
+	verifierClaim = "verifier"
+	nextClaim     = "next"
+	partyClaim    = "azp"
+
+	verifierLength  = 32
+	exchangeTimeout = 10 * time.Second
+)
+
+// NewProvider returns a Provider using the openid, profile, and email scopes,
+// which maps the sub claim to the session, and lands users on "/".
+func NewProvider(issuer, clientID, clientSecret, redirectURL string) *Provider {
+	return &Provider{
+		Issuer:       issuer,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "profile", "email"},
+		Claims:       map[string]string{tonic.SubjectClaim: tonic.SubjectClaim},
+		Landing:      "/",
+		Client:       http.DefaultClient,
+		TTL:          DefaultTTL,
+	}
+}
+
+// Register the login route on the given path, and the callback route on the
+// path of the provider's RedirectURL. The login path is typically used as the
+// redirect for cookie.Authenticate, so users are returned to the page they
+// asked for once they have logged in. An error is returned if the callback path
+// is outside the cookie path, since the state cookie would not be sent to it.
+func Register(r *gin.Engine, security config.Security, provider *Provider, login string) error {
+	callback, err := provider.callbackPath(security)
+
+	if err != nil {
+		return err
+	}
+
+	r.GET(login, provider.Login(security))
+	r.GET(callback, provider.Callback(security))
+
+	return nil
+}
+
+// Login returns a handler that sends the user to the provider to log in. The
+// state, nonce, and PKCE verifier for the login are held in an encrypted
+// cookie, along with any local next parameter. If the security settings are
+// invalid, or the discovery document cannot be fetched, the handler will fail
+// with http.StatusInternalServerError, and the reason is passed to c.Error. The
+// path of the RedirectURL must be within the cookie path.
+func (p *Provider) Login(security config.Security) gin.HandlerFunc {
+	signatory, invalid := states(security)
+
+	if invalid == nil {
+		_, invalid = p.callbackPath(security)
+	}
+
+	return func(c *gin.Context) {
+		err := invalid
+
+		var location string
+
+		if err == nil {
+			location, err = p.authorize(c, security, signatory)
+		}
+
+		if err != nil {
+			fail(c, http.StatusInternalServerError, err)
+
+			return
+		}
+
+		c.Redirect(http.StatusSeeOther, location)
+	}
+}
+
+// Callback returns a handler for the provider's redirect back to the service.
+// The code is exchanged for an ID token, which is validated against the
+// provider's JWKS, and the mapped claims are used to drop a session cookie.
+// The user is then sent to the next URL given to Login, or the Landing URL.
+// Failed logins return http.StatusUnauthorized, and the reason is passed to
+// c.Error.
+func (p *Provider) Callback(security config.Security) gin.HandlerFunc {
+	signatory, invalid := states(security)
+
+	return func(c *gin.Context) {
+		err := invalid
+
+		var next string
+
+		if err == nil {
+			next, err = p.callback(c, security, signatory)
+		}
+
+		switch {
+		case invalid != nil, errors.Is(err, ErrDiscoveryFailed):
+			fail(c, http.StatusInternalServerError, err)
+		case err != nil:
+			fail(c, http.StatusUnauthorized, err)
+		default:
+			if err = cookie.Drop(c, security, p.mapped()...); err != nil {
+				fail(c, http.StatusInternalServerError, err)
+
+				return
+			}
+
+			c.Redirect(http.StatusSeeOther, next)
+		}
+	}
+}
+
+// authorize returns the provider's authorization URL for a new login, setting
+// the state cookie.
+func (p *Provider) authorize(c *gin.Context, security config.Security, signatory *tonic.Signatory) (string, error) {
+	discovery, err := p.Discover(c.Request.Context())
+
+	if err != nil {
+		return "", err
+	}
+
+	location, err := url.Parse(discovery.AuthorizationEndpoint)
+
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrDiscoveryFailed, err.Error())
+	}
+
+	state, nonce, verifier := tonic.GenerateID(), tonic.GenerateID(), generateVerifier()
+	next := c.Query(cookie.NextParam)
+
+	if !cookie.Local(next) {
+		next = ""
+	}
+
+	token, err := signatory.Issue(map[string]any{
+		stateClaim:    state,
+		nonceClaim:    nonce,
+		verifierClaim: verifier,
+		nextClaim:     next,
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := location.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", p.RedirectURL)
+	query.Set("scope", strings.Join(p.Scopes, " "))
+	query.Set(stateClaim, state)
+	query.Set(nonceClaim, nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	location.RawQuery = query.Encode()
+
+	http.SetCookie(c.Writer, bake(security, token, int(StateTTL.Seconds())))
+
+	return location.String(), nil
+}
+
+// callback completes a login, setting the mapped claims on the context and
+// returning the URL the user should be sent to. The state cookie is cleared.
+func (p *Provider) callback(c *gin.Context, security config.Security, signatory *tonic.Signatory) (string, error) {
+	token, err := c.Cookie(security.Cookie.Prefixed(StateName))
+
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidState, tonic.ErrMissingToken)
+	}
+
+	http.SetCookie(c.Writer, bake(security, "", -1))
+
+	if reason := c.Query("error"); reason != "" {
+		return "", fmt.Errorf("%w: %s %s", ErrProvider, reason, c.Query("error_description"))
+	}
+
+	login, err := signatory.Parse(token)
+
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidState, err)
+	}
+
+	state, _ := login[stateClaim].(string)
+
+	if subtle.ConstantTimeCompare([]byte(state), []byte(c.Query(stateClaim))) != 1 {
+		return "", fmt.Errorf("%w: state does not match", ErrInvalidState)
+	}
+
+	discovery, err := p.Discover(c.Request.Context())
+
+	if err != nil {
+		return "", err
+	}
+
+	verifier, _ := login[verifierClaim].(string)
+	idToken, err := p.exchange(c, discovery, c.Query("code"), verifier)
+
+	if err != nil {
+		return "", err
+	}
+
+	nonce, _ := login[nonceClaim].(string)
+	claims, err := p.validate(discovery, security, idToken, nonce)
+
+	if err != nil {
+		return "", err
+	}
+
+	for claim, key := range p.Claims {
+		if v, ok := claims[claim]; ok {
+			c.Set(key, v)
+		}
+	}
+
+	if next, _ := login[nextClaim].(string); cookie.Local(next) {
+		return next, nil
+	}
+
+	return p.Landing, nil
+}
+
+// exchange the code for an ID token at the provider's token endpoint.
+func (p *Provider) exchange(c *gin.Context, discovery *Discovery, code, verifier string) (string, error) {
+	var response struct {
+		IDToken     string `json:"id_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"code_verifier": {verifier},
+	}
+
+	if p.ClientSecret == "" {
+		form.Set("client_id", p.ClientID)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), exchangeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint,
+		strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrExchangeFailed, err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if p.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	res, err := p.client().Do(req)
+
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrExchangeFailed, err.Error())
+	}
+
+	defer func() { _ = res.Body.Close() }()
+
+	if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("%w: %s returned %d", ErrExchangeFailed, discovery.TokenEndpoint, res.StatusCode)
+	}
+
+	switch {
+	case res.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("%w: %s %s", ErrExchangeFailed, response.Error, response.Description)
+	case response.IDToken == "":
+		return "", fmt.Errorf("%w: no ID token", ErrExchangeFailed)
+	}
+
+	return response.IDToken, nil
+}
+
+// validate the ID token, returning its claims. The token must be signed by one
+// of the provider's keys, and be issued by the provider for this client with
+// the nonce for the login.
+func (p *Provider) validate(d *Discovery, security config.Security, token, nonce string) (map[string]any, error) {
+	p.mu.Lock()
+	signatory := &tonic.Signatory{
+		Source:   p.keys,
+		Methods:  d.Algorithms,
+		Issuer:   p.Issuer,
+		Audience: []string{p.ClientID},
+		Leeway:   security.Leeway,
+	}
+	p.mu.Unlock()
+
+	claims, err := signatory.Parse(token)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	if claimed, _ := claims[nonceClaim].(string); subtle.ConstantTimeCompare([]byte(claimed), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIDToken)
+	}
+
+	if party, ok := claims[partyClaim]; ok && party != p.ClientID {
+		return nil, fmt.Errorf("%w: authorized party is %v", ErrInvalidIDToken, party)
+	}
+
+	return claims, nil
+}
+
+// mapped returns the session claims the ID token claims are mapped to.
+func (p *Provider) mapped() []string {
+	claims := make([]string, 0, len(p.Claims))
+
+	for _, claim := range p.Claims {
+		claims = append(claims, claim)
+	}
+
+	return claims
+}
+
+// callbackPath returns the path of the RedirectURL, which must be within the
+// cookie path so the state cookie is sent to the callback.
+func (p *Provider) callbackPath(security config.Security) (string, error) {
+	callback, err := url.Parse(p.RedirectURL)
+
+	if err != nil {
+		return "", fmt.Errorf("invalid redirect URL: %w", err)
+	}
+
+	scope, path := security.Cookie.Scope(), callback.Path
+
+	if path == "" {
+		path = "/"
+	}
+
+	if path != scope && !strings.HasPrefix(path, strings.TrimSuffix(scope, "/")+"/") {
+		return "", fmt.Errorf("%w: callback %s is outside the cookie path %s", config.ErrInvalidCookie,
+			path, scope)
+	}
+
+	return path, nil
+}
+
+// client returns the Client, or http.DefaultClient if no Client is set.
+func (p *Provider) client() *http.Client {
+	if p.Client == nil {
+		return http.DefaultClient
+	}
+
+	return p.Client
+}
+
+// states returns a Signatory for the state cookie, sharing its keys with every
+// other Signatory for the security settings. The state is encrypted so the
+// PKCE verifier and nonce cannot be read by the client.
+func states(security config.Security) (*tonic.Signatory, error) {
+	if err := security.Cookie.Validate(security.Domain, security.SecureCookies()); err != nil {
+		return nil, err
+	}
+
+	signatory, err := tonic.SharedSignatory(security)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid security settings: %w", err)
+	}
+
+	signatory.Type = StateType
+	signatory.TTL = StateTTL
+	signatory.Encrypt = true
+
+	if err = signatory.CheckEncryption(); err != nil {
+		return nil, fmt.Errorf("cannot encrypt state: %w", err)
+	}
+
+	return signatory, nil
+}
+
+// bake a state cookie holding the given value. The cookie uses SameSite=Lax
+// regardless of the Cookie settings, since the provider's redirect back to
+// the service is a cross site request.
+func bake(security config.Security, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     security.Cookie.Prefixed(StateName),
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     security.Cookie.Scope(),
+		Domain:   security.Domain,
+		Secure:   security.SecureCookies(),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// generateVerifier returns a new PKCE code verifier. generateVerifier will
+// panic if it fails.
+func generateVerifier() string {
+	verifier := make([]byte, verifierLength)
+
+	if _, err := io.ReadFull(rand.Reader, verifier); err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(verifier)
+}
+
+// fail the request with the given status, passing the error to c.Error.
+func fail(c *gin.Context, status int, err error) {
+	//nolint:errcheck // Gin is handling this for us.
+	_ = c.Error(fmt.Errorf("failed to log in: %w", err))
+	c.Abort()
+	c.String(status, http.StatusText(status))
+}
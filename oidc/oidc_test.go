@@ -0,0 +1,367 @@
+package oidc_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/cookie"
+	"github.com/domdavis/tonic/oidc"
+	"github.com/domdavis/tonic/register"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Provider is a stand-in OpenID Connect provider. Every login is for the
+// Subject, and the claims in Extra are added to the ID token, which is issued
+// by the Signatory.
+type Provider struct {
+	*httptest.Server
+
+	Subject     string
+	Extra       map[string]any
+	Signatory   *tonic.Signatory
+	Discoveries int
+
+	mu    sync.Mutex
+	codes map[string]url.Values
+}
+
+const (
+	clientID     = "client"
+	clientSecret = "secret"
+)
+
+// NewProvider returns a running stand-in provider that signs ID tokens with
+// the RSA test key. NewProvider panics if the key cannot be loaded.
+func NewProvider() *Provider {
+	p := &Provider{Subject: "alice", codes: map[string]url.Values{}}
+	router := gin.New()
+	p.Server = httptest.NewServer(router)
+
+	signatory, err := tonic.NewSignatory(config.Security{
+		PrivateKey: "../testdata/rsa.key",
+		Issuer:     p.URL,
+		Audience:   clientID,
+		SessionTTL: time.Minute,
+	})
+
+	if err != nil {
+		panic(err)
+	}
+
+	p.Signatory = signatory
+
+	register.JWKS(router, signatory)
+	router.GET(oidc.DiscoveryPath, p.discover)
+	router.GET("/authorize", p.authorize)
+	router.POST("/token", p.token)
+
+	return p
+}
+
+func (p *Provider) discover(c *gin.Context) {
+	p.mu.Lock()
+	p.Discoveries++
+	p.mu.Unlock()
+
+	c.JSON(http.StatusOK, oidc.Discovery{
+		Issuer:                p.URL,
+		AuthorizationEndpoint: p.URL + "/authorize",
+		TokenEndpoint:         p.URL + "/token",
+		JWKSURI:               p.URL + register.JWKSPath,
+		Algorithms:            []string{"RS256"},
+	})
+}
+
+// authorize logs the Subject in straight away, redirecting back to the client.
+func (p *Provider) authorize(c *gin.Context) {
+	query := c.Request.URL.Query()
+
+	if query.Get("client_id") != clientID || query.Get("code_challenge_method") != "S256" {
+		c.String(http.StatusBadRequest, "invalid_request")
+
+		return
+	}
+
+	code := tonic.GenerateID()
+
+	p.mu.Lock()
+	p.codes[code] = query
+	p.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+
+	c.Redirect(http.StatusFound, redirect.String())
+}
+
+// token exchanges a code for an ID token, checking the client credentials and
+// PKCE verifier.
+func (p *Provider) token(c *gin.Context) {
+	p.mu.Lock()
+	query, ok := p.codes[c.PostForm("code")]
+	delete(p.codes, c.PostForm("code"))
+	p.mu.Unlock()
+
+	id, secret, _ := c.Request.BasicAuth()
+	verifier := sha256.Sum256([]byte(c.PostForm("code_verifier")))
+
+	switch {
+	case id != clientID || secret != clientSecret:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+	case !ok || c.PostForm("redirect_uri") != query.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != query.Get("code_challenge"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+	default:
+		claims := map[string]any{"sub": p.Subject, "nonce": query.Get("nonce")}
+
+		for k, v := range p.Extra {
+			claims[k] = v
+		}
+
+		token, _ := p.Signatory.Issue(claims)
+		c.JSON(http.StatusOK, gin.H{"id_token": token, "token_type": "Bearer"})
+	}
+}
+
+// Service is a running service that logs users in through a provider.
+type Service struct {
+	*httptest.Server
+
+	// Browser used to visit the service, which follows redirects.
+	Browser *http.Client
+
+	// Errors reported by the last request to the service.
+	Errors []*gin.Error
+}
+
+// NewService returns a running service that logs users in through the
+// provider at the given issuer, using the given client secret.
+func NewService(issuer, secret string) *Service {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+	jar, _ := cookiejar.New(nil)
+	router := gin.New()
+	s := &Service{Server: httptest.NewServer(router), Browser: &http.Client{Jar: jar}}
+
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		s.Errors = c.Errors
+	})
+
+	provider := oidc.NewProvider(issuer, clientID, secret, s.URL+"/callback")
+
+	if err := oidc.Register(router, security, provider, "/login"); err != nil {
+		panic(err)
+	}
+
+	router.GET("/", cookie.Authenticate(security, "/login"), func(c *gin.Context) {
+		c.String(http.StatusOK, "home %s", tonic.Get[string](c, tonic.SubjectClaim))
+	})
+	router.GET("/page", cookie.Authenticate(security, "/login"), func(c *gin.Context) {
+		c.String(http.StatusOK, "page %s", tonic.Get[string](c, tonic.SubjectClaim))
+	})
+
+	return s
+}
+
+// Stop following redirects back to the service's callback.
+func (s *Service) Stop() {
+	s.Browser.CheckRedirect = func(req *http.Request, _ []*http.Request) error {
+		if req.URL.Path == "/callback" {
+			return http.ErrUseLastResponse
+		}
+
+		return nil
+	}
+}
+
+func Example() {
+	provider := NewProvider()
+	defer provider.Close()
+
+	service := NewService(provider.URL, clientSecret)
+	defer service.Close()
+
+	res, _ := service.Browser.Get(service.URL + "/page")
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+
+	fmt.Println(res.StatusCode, string(body))
+
+	// Output:
+	// 200 page alice
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Callbacks must be within the cookie path", func(t *testing.T) {
+		t.Parallel()
+
+		security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+		security.Cookie.Path = "/app"
+
+		for redirect, valid := range map[string]bool{
+			"http://localhost/app":          true,
+			"http://localhost/app/callback": true,
+			"http://localhost/callback":     false,
+			"http://localhost/application":  false,
+			"http://localhost":              false,
+		} {
+			provider := oidc.NewProvider("http://localhost", clientID, clientSecret, redirect)
+			err := oidc.Register(gin.New(), security, provider, "/app/login")
+
+			if valid {
+				assert.NoError(t, err, redirect)
+			} else {
+				assert.ErrorIs(t, err, config.ErrInvalidCookie, redirect)
+			}
+		}
+	})
+
+	t.Run("Logins fail if the callback is outside the cookie path", func(t *testing.T) {
+		t.Parallel()
+
+		security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+		security.Cookie.Path = "/app"
+		provider := oidc.NewProvider("http://localhost", clientID, clientSecret, "http://localhost/callback")
+		router := gin.New()
+		router.GET("/app/login", provider.Login(security))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app/login", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
+
+func TestProvider_Callback(t *testing.T) {
+	t.Parallel()
+
+	start := func(t *testing.T, p *Provider, secret string) *Service {
+		t.Helper()
+
+		if p == nil {
+			p = NewProvider()
+		}
+
+		s := NewService(p.URL, secret)
+
+		t.Cleanup(p.Close)
+		t.Cleanup(s.Close)
+
+		return s
+	}
+
+	t.Run("Users land on the landing page without a next parameter", func(t *testing.T) {
+		t.Parallel()
+
+		s := start(t, nil, clientSecret)
+		res, err := s.Browser.Get(s.URL + "/login?next=https://example.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "/", res.Request.URL.Path)
+	})
+
+	t.Run("Callbacks without a login are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		s := start(t, nil, clientSecret)
+		res, err := s.Browser.Get(s.URL + "/callback?code=code&state=state")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.ErrorIs(t, s.Errors[0], oidc.ErrInvalidState)
+		assert.ErrorIs(t, s.Errors[0], tonic.ErrMissingToken)
+	})
+
+	t.Run("Callbacks with the wrong state are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		s := start(t, nil, clientSecret)
+		s.Stop()
+
+		res, err := s.Browser.Get(s.URL + "/login")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+
+		res, err = s.Browser.Get(s.URL + "/callback?code=code&state=forged")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.ErrorIs(t, s.Errors[0], oidc.ErrInvalidState)
+
+		// The state cookie is cleared, so the login cannot be retried.
+		res, err = s.Browser.Get(s.URL + "/callback?code=code&state=forged")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.ErrorIs(t, s.Errors[0], tonic.ErrMissingToken)
+	})
+
+	t.Run("Provider errors are reported", func(t *testing.T) {
+		t.Parallel()
+
+		s := start(t, nil, clientSecret)
+		s.Stop()
+
+		_, err := s.Browser.Get(s.URL + "/login")
+
+		assert.NoError(t, err)
+
+		res, err := s.Browser.Get(s.URL + "/callback?error=access_denied")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.ErrorIs(t, s.Errors[0], oidc.ErrProvider)
+	})
+
+	t.Run("Failed exchanges are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		s := start(t, nil, "wrong")
+		res, err := s.Browser.Get(s.URL + "/page")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.ErrorIs(t, s.Errors[0], oidc.ErrExchangeFailed)
+	})
+
+	t.Run("ID tokens must be valid", func(t *testing.T) {
+		t.Parallel()
+
+		for name, invalidate := range map[string]func(p *Provider){
+			"nonce":    func(p *Provider) { p.Extra = map[string]any{"nonce": "replayed"} },
+			"audience": func(p *Provider) { p.Signatory.Audience = []string{"other"} },
+			"issuer":   func(p *Provider) { p.Signatory.Issuer = "https://example.com" },
+			"authorized party": func(p *Provider) {
+				p.Signatory.Audience = []string{clientID, "other"}
+				p.Extra = map[string]any{"azp": "other"}
+			},
+		} {
+			p := NewProvider()
+			invalidate(p)
+
+			s := start(t, p, clientSecret)
+
+			res, err := s.Browser.Get(s.URL + "/page")
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, name)
+			assert.ErrorIs(t, s.Errors[0], oidc.ErrInvalidIDToken, name)
+		}
+	})
+}
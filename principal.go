@@ -0,0 +1,108 @@
+package tonic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Claims holds the registered claims. Embed Claims in a struct to sign and
+// validate it using Sign and Validate. The exp, nbf, iat, iss, aud, and gen
+// claims are set by the Signatory when signing, and jti is set if it's empty.
+//
+//nolint:tagliatelle // Claim names are defined by RFC 7519.
+type Claims struct {
+	Issuer     string           `json:"iss,omitempty"`
+	Subject    string           `json:"sub,omitempty"`
+	Audience   jwt.ClaimStrings `json:"aud,omitempty"`
+	ExpiresAt  *jwt.NumericDate `json:"exp,omitempty"`
+	NotBefore  *jwt.NumericDate `json:"nbf,omitempty"`
+	IssuedAt   *jwt.NumericDate `json:"iat,omitempty"`
+	ID         string           `json:"jti,omitempty"`
+	Generation int64            `json:"gen,omitempty"`
+}
+
+// PrincipalKey is the context key used to hold the claims of the authenticated
+// principal. Use Principal to get the claims in a type safe manner.
+const PrincipalKey = "tonic.principal"
+
+// Sign the given claims using the Signatory. The claims are encoded as JSON,
+// so T would typically be a struct embedding Claims, with a JSON tag for each
+// private claim.
+func Sign[T any](s *Signatory, claims T) (string, error) {
+	payload, err := convert[jwt.MapClaims](claims)
+
+	if err != nil {
+		return "", err
+	}
+
+	return s.Issue(payload)
+}
+
+// Validate the given token using the Signatory, returning the claims decoded
+// into T. If the token is valid the claims are also set on the context under
+// PrincipalKey. The errors returned are the same as for Signatory.Validate,
+// with ErrInvalidClaim returned if the claims cannot be decoded into T.
+func Validate[T any](ctx *gin.Context, s *Signatory, tokenString string) (T, error) {
+	var principal T
+
+	claims, err := s.parse(tokenString, jwt.WithJSONNumber())
+
+	if err != nil {
+		return principal, err
+	}
+
+	if principal, err = convert[T](claims); err != nil {
+		return principal, err
+	}
+
+	ctx.Set(PrincipalKey, principal)
+
+	return principal, nil
+}
+
+// Principal returns the claims of the authenticated principal from the
+// context. Claims set by Validate[T] are returned as is, while claims set by
+// Signatory.Validate, which holds them as jwt.MapClaims, are decoded into T.
+// Returns false if there is no principal, or it cannot be decoded into T.
+func Principal[T any](ctx *gin.Context) (T, bool) {
+	var principal T
+
+	v, ok := ctx.Get(PrincipalKey)
+
+	if !ok {
+		return principal, false
+	}
+
+	if principal, ok = v.(T); ok {
+		return principal, true
+	}
+
+	principal, err := convert[T](v)
+
+	return principal, err == nil
+}
+
+// convert from one claims representation to another by round tripping through
+// JSON. Numbers are kept as json.Number so large integers are not rounded.
+func convert[T any](from any) (T, error) {
+	var to T
+
+	b, err := json.Marshal(from)
+
+	if err != nil {
+		return to, fmt.Errorf("%w: %s", ErrInvalidClaim, err.Error())
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+
+	if err = decoder.Decode(&to); err != nil {
+		return to, fmt.Errorf("%w: %s", ErrInvalidClaim, err.Error())
+	}
+
+	return to, nil
+}
@@ -0,0 +1,159 @@
+package tonic_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type User struct {
+	tonic.Claims
+
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+	Count int64    `json:"count"`
+}
+
+func ExampleSign() {
+	s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+	token, _ := tonic.Sign(s, User{
+		Claims: tonic.Claims{Subject: "1234"},
+		Name:   "user",
+		Roles:  []string{"admin"},
+	})
+
+	ctx := &gin.Context{}
+	user, err := tonic.Validate[User](ctx, s, token)
+
+	fmt.Println(err, user.Subject, user.Name, user.Roles)
+
+	// Handlers further down the chain can get the principal from the context.
+	principal, ok := tonic.Principal[User](ctx)
+
+	fmt.Println(ok, principal.Name, principal.ExpiresAt.Sub(principal.IssuedAt.Time))
+
+	// Output:
+	// <nil> 1234 user [admin]
+	// true user 1h0m0s
+}
+
+func TestSign(t *testing.T) {
+	t.Run("Large integers are not rounded", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		token, err := tonic.Sign(s, User{Count: 1<<62 + 1})
+
+		assert.NoError(t, err)
+
+		user, err := tonic.Validate[User](&gin.Context{}, s, token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1<<62+1), user.Count)
+	})
+
+	t.Run("Claims must encode to a JSON object", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+
+		_, err := tonic.Sign(s, "claims")
+
+		assert.ErrorIs(t, err, tonic.ErrInvalidClaim)
+
+		_, err = tonic.Sign(s, map[string]any{"c": make(chan int)})
+
+		assert.ErrorIs(t, err, tonic.ErrInvalidClaim)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("Invalid tokens are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		ctx := &gin.Context{}
+
+		_, err := tonic.Validate[User](ctx, s, "garbage")
+
+		assert.ErrorIs(t, err, tonic.ErrMalformed)
+
+		_, ok := tonic.Principal[User](ctx)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("Claims that don't fit the type are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		token, err := s.Issue(jwt.MapClaims{"name": 1})
+
+		assert.NoError(t, err)
+
+		_, err = tonic.Validate[User](&gin.Context{}, s, token)
+
+		assert.ErrorIs(t, err, tonic.ErrInvalidClaim)
+	})
+}
+
+func TestValidate_revocations(t *testing.T) {
+	t.Run("Revoked subjects are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{
+			Secret: "secret", TTL: time.Hour, Revocations: tonic.NewMemoryRevocations(),
+		}
+
+		assert.NoError(t, s.Revocations.RevokeAll("1234"))
+
+		token, err := tonic.Sign(s, User{Claims: tonic.Claims{Subject: "1234"}})
+
+		assert.NoError(t, err)
+
+		user, err := tonic.Validate[User](&gin.Context{}, s, token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), user.Generation)
+		assert.NoError(t, s.Revocations.RevokeAll("1234"))
+
+		_, err = tonic.Validate[User](&gin.Context{}, s, token)
+
+		assert.ErrorIs(t, err, tonic.ErrRevoked)
+	})
+}
+
+func TestPrincipal(t *testing.T) {
+	t.Run("Claims set by Signatory.Validate can be decoded", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		token, err := s.Issue(jwt.MapClaims{"name": "user", "sub": "1234"})
+
+		assert.NoError(t, err)
+
+		ctx := &gin.Context{}
+
+		assert.NoError(t, s.Validate(ctx, token))
+
+		user, ok := tonic.Principal[User](ctx)
+
+		assert.True(t, ok)
+		assert.Equal(t, "user", user.Name)
+		assert.Equal(t, "1234", user.Subject)
+
+		claims, ok := tonic.Principal[jwt.MapClaims](ctx)
+
+		assert.True(t, ok)
+		assert.Equal(t, "user", claims["name"])
+
+		_, ok = tonic.Principal[string](ctx)
+
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,189 @@
+package register
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// A TokenValidator validates and revokes tokens. A tonic.Signatory is a
+// TokenValidator, and will reject revoked tokens if it has a RevocationStore.
+type TokenValidator interface {
+	// Parse and validate a token, returning its claims.
+	Parse(token string) (jwt.MapClaims, error)
+
+	// Revoke a valid token so it can no longer be used.
+	Revoke(token string) error
+}
+
+// Introspection is sent by the introspection endpoint, as defined in RFC 7662.
+// Only Active is set for inactive tokens.
+//
+//nolint:tagliatelle // Field names are defined by RFC 7662.
+type Introspection struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Expires   int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  []string `json:"aud,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+}
+
+// Paths the introspection and revocation endpoints are registered on.
+const (
+	IntrospectionPath = "/oauth/introspect"
+	RevocationPath    = "/oauth/revoke"
+)
+
+// tokenParam holds the token sent to the introspection and revocation
+// endpoints.
+const tokenParam = "token"
+
+// IntrospectionEndpoint registers an OAuth 2.0 token introspection endpoint
+// (RFC 7662), allowing services that cannot validate tokens themselves to ask
+// if a token is active. Clients authenticate in the same way as for the
+// TokenEndpoint. The token is validated by each of the validators in turn, and
+// is active if any of them accept it. Tokens are not active once revoked if
+// the validators have a RevocationStore.
+func IntrospectionEndpoint(r *gin.Engine, clients ClientRegistry, validators ...TokenValidator) {
+	r.POST(IntrospectionPath, func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+
+		if _, ok := authenticateClient(c, clients); !ok {
+			return
+		}
+
+		token := c.PostForm(tokenParam)
+
+		if token == "" {
+			oauthError(c, http.StatusBadRequest, invalidRequest, "token is required")
+
+			return
+		}
+
+		claims, _ := validate(token, validators)
+
+		c.JSON(http.StatusOK, introspect(claims))
+	})
+}
+
+// RevocationEndpoint registers an OAuth 2.0 token revocation endpoint
+// (RFC 7009). Clients authenticate in the same way as for the TokenEndpoint,
+// and can only revoke tokens issued to them. Tokens not issued to a client can
+// only be revoked by clients with RevokeAny set.
+// The token is revoked by the first validator that accepts it, which must have
+// a RevocationStore. Use the same store with tonic.Revocable so revoked tokens
+// are rejected by jwt.Authenticate. As required by RFC 7009, invalid tokens are
+// ignored.
+func RevocationEndpoint(r *gin.Engine, clients ClientRegistry, validators ...TokenValidator) {
+	r.POST(RevocationPath, func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+
+		client, ok := authenticateClient(c, clients)
+
+		if !ok {
+			return
+		}
+
+		token := c.PostForm(tokenParam)
+
+		if token == "" {
+			oauthError(c, http.StatusBadRequest, invalidRequest, "token is required")
+
+			return
+		}
+
+		claims, validator := validate(token, validators)
+
+		if validator == nil {
+			c.Status(http.StatusOK)
+
+			return
+		}
+
+		if id, ok := claims[ClientIDClaim]; (ok && id != client.ID) || (!ok && !client.RevokeAny) {
+			oauthError(c, http.StatusBadRequest, unauthorizedClient, "token was not issued to this client")
+
+			return
+		}
+
+		if err := validator.Revoke(token); err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(fmt.Errorf("failed to revoke token: %w", err))
+			oauthError(c, http.StatusInternalServerError, serverError, "failed to revoke token")
+
+			return
+		}
+
+		c.Status(http.StatusOK)
+	})
+}
+
+// validate the token, returning its claims and the validator that accepted it.
+// A nil validator is returned if no validator accepted the token.
+//
+//nolint:ireturn // The validator is one of those given.
+func validate(token string, validators []TokenValidator) (jwt.MapClaims, TokenValidator) {
+	for _, validator := range validators {
+		if claims, err := validator.Parse(token); err == nil {
+			return claims, validator
+		}
+	}
+
+	return nil, nil
+}
+
+// introspect returns the introspection response for the claims. Nil claims are
+// inactive.
+func introspect(claims jwt.MapClaims) Introspection {
+	if claims == nil {
+		return Introspection{}
+	}
+
+	introspection := Introspection{
+		Active:    true,
+		Expires:   numeric(claims["exp"]),
+		IssuedAt:  numeric(claims["iat"]),
+		NotBefore: numeric(claims["nbf"]),
+	}
+
+	introspection.Scope, _ = claims[scopeClaim].(string)
+	introspection.ClientID, _ = claims[ClientIDClaim].(string)
+	introspection.Subject, _ = claims[subjectClaim].(string)
+	introspection.Issuer, _ = claims["iss"].(string)
+	introspection.ID, _ = claims["jti"].(string)
+
+	switch aud := claims["aud"].(type) {
+	case string:
+		introspection.Audience = []string{aud}
+	case []any:
+		for _, v := range aud {
+			if s, ok := v.(string); ok {
+				introspection.Audience = append(introspection.Audience, s)
+			}
+		}
+	}
+
+	return introspection
+}
+
+// numeric returns the value of a NumericDate claim, or 0 if it isn't a number.
+func numeric(v any) int64 {
+	switch n := v.(type) {
+	case float64:
+		return int64(n)
+	case json.Number:
+		i, _ := n.Int64()
+
+		return i
+	default:
+		return 0
+	}
+}
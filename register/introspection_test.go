@@ -0,0 +1,290 @@
+package register_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/jwt"
+	"github.com/domdavis/tonic/register"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleIntrospectionEndpoint() {
+	var introspection register.Introspection
+
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour, RefreshTTL: time.Hour * 24}
+	access, _ := jwt.AccessIssuer(security, nil)
+	refresh, _ := jwt.RefreshIssuer(security, jwt.NewMemoryStore(), nil)
+	clients := register.NewClients(register.Client{
+		ID:     "reporting",
+		Secret: Hash("s3cr3t"),
+		Scopes: []string{"reports:read"},
+	}, register.Client{
+		ID:     "resource-server",
+		Secret: Hash("r3s0urc3"),
+	})
+
+	router := gin.New()
+	register.TokenEndpoint(router, clients, access, refresh)
+	register.IntrospectionEndpoint(router, clients, access, refresh)
+
+	_, response := Grant(router, "reporting", "s3cr3t", url.Values{
+		"grant_type": {register.ClientCredentialsGrant},
+	})
+
+	w := Post(router, register.IntrospectionPath, "resource-server", "r3s0urc3", url.Values{
+		"token": {response.AccessToken},
+	})
+
+	_ = json.Unmarshal(w.Body.Bytes(), &introspection)
+
+	fmt.Println(w.Code, introspection.Active, introspection.Subject, introspection.Scope)
+
+	w = Post(router, register.IntrospectionPath, "resource-server", "r3s0urc3", url.Values{
+		"token": {"invalid"},
+	})
+
+	fmt.Println(w.Code, w.Body.String())
+
+	// Output:
+	// 200 true reporting reports:read
+	// 200 {"active":false}
+}
+
+func TestIntrospectionEndpoint(t *testing.T) {
+	t.Parallel()
+
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour, RefreshTTL: time.Hour * 24}
+	access, err := jwt.AccessIssuer(security, nil)
+
+	assert.NoError(t, err)
+
+	refresh, err := jwt.RefreshIssuer(security, jwt.NewMemoryStore(), nil)
+
+	assert.NoError(t, err)
+
+	clients := register.NewClients(register.Client{
+		ID:      "client",
+		Secret:  Hash("secret"),
+		Scopes:  []string{"read"},
+		Refresh: true,
+	})
+
+	router := gin.New()
+	register.TokenEndpoint(router, clients, access, refresh)
+	register.IntrospectionEndpoint(router, clients, access, refresh)
+
+	_, issued := Grant(router, "client", "secret", url.Values{
+		"grant_type": {register.ClientCredentialsGrant},
+	})
+
+	t.Run("Refresh tokens can be introspected", func(t *testing.T) {
+		t.Parallel()
+
+		var introspection register.Introspection
+
+		w := Post(router, register.IntrospectionPath, "client", "secret", url.Values{
+			"token": {issued.RefreshToken},
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &introspection))
+		assert.True(t, introspection.Active)
+		assert.Equal(t, "client", introspection.ClientID)
+		assert.NotEmpty(t, introspection.ID)
+		assert.InDelta(t, time.Now().Add(time.Hour*24).Unix(), introspection.Expires, 5)
+	})
+
+	t.Run("Clients must authenticate", func(t *testing.T) {
+		t.Parallel()
+
+		w := Post(router, register.IntrospectionPath, "client", "wrong", url.Values{
+			"token": {issued.AccessToken},
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotContains(t, w.Body.String(), "active")
+	})
+
+	t.Run("A token is required", func(t *testing.T) {
+		t.Parallel()
+
+		w := Post(router, register.IntrospectionPath, "client", "secret", url.Values{})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_request")
+	})
+}
+
+func TestRevocationEndpoint(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Revoked tokens are rejected by jwt.Authenticate", func(t *testing.T) {
+		t.Parallel()
+
+		store := tonic.NewMemoryRevocations()
+		security := config.Security{Secret: "secret", SessionTTL: time.Hour, RefreshTTL: time.Hour * 24}
+		access, err := jwt.AccessIssuer(security, store)
+
+		assert.NoError(t, err)
+
+		refresh, err := jwt.RefreshIssuer(security, jwt.NewMemoryStore(), store)
+
+		assert.NoError(t, err)
+
+		clients := register.NewClients(register.Client{
+			ID:      "client",
+			Secret:  Hash("secret"),
+			Refresh: true,
+		})
+
+		router := gin.New()
+		router.Use(tonic.Revocable(store))
+		register.TokenEndpoint(router, clients, access, refresh)
+		register.IntrospectionEndpoint(router, clients, access, refresh)
+		register.RevocationEndpoint(router, clients, access, refresh)
+		router.GET("/api", jwt.Authenticate(security), func(c *gin.Context) {
+			c.String(http.StatusOK, tonic.Get[string](c, tonic.SubjectClaim))
+		})
+
+		_, issued := Grant(router, "client", "secret", url.Values{
+			"grant_type": {register.ClientCredentialsGrant},
+		})
+
+		call := func() int {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			jwt.Set(req, issued.AccessToken)
+			router.ServeHTTP(w, req)
+
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, call())
+
+		for _, token := range []string{issued.AccessToken, issued.RefreshToken} {
+			w := Post(router, register.RevocationPath, "client", "secret", url.Values{"token": {token}})
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			w = Post(router, register.IntrospectionPath, "client", "secret", url.Values{"token": {token}})
+
+			assert.JSONEq(t, `{"active":false}`, w.Body.String())
+		}
+
+		assert.Equal(t, http.StatusUnauthorized, call())
+
+		w, _ := Grant(router, "client", "secret", url.Values{
+			"grant_type":    {register.RefreshTokenGrant},
+			"refresh_token": {issued.RefreshToken},
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Clients can only revoke their own tokens", func(t *testing.T) {
+		t.Parallel()
+
+		security := config.Security{Secret: "secret", SessionTTL: time.Hour, RefreshTTL: time.Hour * 24}
+		access, err := jwt.AccessIssuer(security, tonic.NewMemoryRevocations())
+
+		assert.NoError(t, err)
+
+		refresh, err := jwt.RefreshIssuer(security, jwt.NewMemoryStore(), nil)
+
+		assert.NoError(t, err)
+
+		clients := register.NewClients(register.Client{
+			ID:     "client",
+			Secret: Hash("secret"),
+		}, register.Client{
+			ID:     "other",
+			Secret: Hash("other"),
+		}, register.Client{
+			ID:        "admin",
+			Secret:    Hash("admin"),
+			RevokeAny: true,
+		})
+
+		router := gin.New()
+		register.TokenEndpoint(router, clients, access, refresh)
+		register.RevocationEndpoint(router, clients, access)
+
+		_, issued := Grant(router, "client", "secret", url.Values{
+			"grant_type": {register.ClientCredentialsGrant},
+		})
+
+		w := Post(router, register.RevocationPath, "other", "other", url.Values{"token": {issued.AccessToken}})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized_client")
+
+		_, err = access.Parse(issued.AccessToken)
+
+		assert.NoError(t, err)
+
+		// Tokens not issued to a client can only be revoked by clients allowed
+		// to revoke any token, but never those issued to another client.
+		token, err := access.Issue(nil)
+
+		assert.NoError(t, err)
+
+		w = Post(router, register.RevocationPath, "other", "other", url.Values{"token": {token}})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized_client")
+
+		w = Post(router, register.RevocationPath, "admin", "admin", url.Values{"token": {issued.AccessToken}})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = Post(router, register.RevocationPath, "admin", "admin", url.Values{"token": {token}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		_, err = access.Parse(token)
+
+		assert.ErrorIs(t, err, tonic.ErrRevoked)
+
+		// Invalid tokens are ignored.
+		w = Post(router, register.RevocationPath, "other", "other", url.Values{"token": {"invalid"}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Revocation fails without a store", func(t *testing.T) {
+		t.Parallel()
+
+		security := config.Security{Secret: "secret", SessionTTL: time.Hour, RefreshTTL: time.Hour * 24}
+		access, err := jwt.AccessIssuer(security, nil)
+
+		assert.NoError(t, err)
+
+		refresh, err := jwt.RefreshIssuer(security, jwt.NewMemoryStore(), nil)
+
+		assert.NoError(t, err)
+
+		clients := register.NewClients(register.Client{ID: "client", Secret: Hash("secret")})
+
+		router := gin.New()
+		register.TokenEndpoint(router, clients, access, refresh)
+		register.RevocationEndpoint(router, clients, access)
+
+		_, issued := Grant(router, "client", "secret", url.Values{
+			"grant_type": {register.ClientCredentialsGrant},
+		})
+
+		w := Post(router, register.RevocationPath, "client", "secret", url.Values{"token": {issued.AccessToken}})
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "server_error")
+	})
+}
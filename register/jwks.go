@@ -0,0 +1,50 @@
+package register
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/domdavis/tonic/jwk"
+	"github.com/gin-gonic/gin"
+)
+
+// A KeyPublisher publishes the public keys used to sign tokens. A
+// tonic.Signatory is a KeyPublisher.
+type KeyPublisher interface {
+	JWKS() jwk.Set
+}
+
+// JWKSPath is the well known path the JWKS endpoint is registered on.
+const JWKSPath = "/.well-known/jwks.json"
+
+// JWKSMaxAge is how long clients may cache the JWKS response for. This is kept
+// short so that rotated keys are picked up quickly.
+const JWKSMaxAge = 15 * time.Minute
+
+// JWKS registers an endpoint that serves the JSON Web Key Set (RFC 7517) for
+// the given publisher, allowing other services to validate tokens without
+// holding the secret. Responses carry Cache-Control and ETag headers, and
+// conditional requests are answered with http.StatusNotModified.
+func JWKS(r *gin.Engine, publisher KeyPublisher) {
+	r.GET(JWKSPath, func(c *gin.Context) {
+		b, _ := json.Marshal(publisher.JWKS())
+		sum := sha256.Sum256(b)
+		tag := fmt.Sprintf("%q", base64.RawURLEncoding.EncodeToString(sum[:]))
+
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d",
+			int(JWKSMaxAge.Seconds())))
+		c.Header("ETag", tag)
+
+		if c.GetHeader("If-None-Match") == tag {
+			c.Status(http.StatusNotModified)
+
+			return
+		}
+
+		c.Data(http.StatusOK, "application/json", b)
+	})
+}
@@ -0,0 +1,101 @@
+package register_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/jwk"
+	"github.com/domdavis/tonic/register"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleJWKS() {
+	signatory, err := tonic.NewSignatory(config.Security{
+		PrivateKey: "../testdata/ecdsa.key",
+	})
+
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	router := gin.New()
+
+	register.JWKS(router, signatory)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest(http.MethodGet, register.JWKSPath, nil)
+
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	router.ServeHTTP(w, req)
+
+	var set jwk.Set
+
+	err = json.Unmarshal(w.Body.Bytes(), &set)
+
+	fmt.Println(w.Code, w.Header().Get("Cache-Control"), err)
+	fmt.Println(len(set.Keys), set.Keys[0].KeyType, set.Keys[0].Algorithm)
+
+	// Output:
+	// 200 public, max-age=900 <nil>
+	// 1 EC ES256
+}
+
+func TestJWKS(t *testing.T) {
+	t.Run("Secrets are not published", func(t *testing.T) {
+		t.Parallel()
+
+		signatory, err := tonic.NewSignatory(config.Security{Secret: "secret"})
+
+		assert.NoError(t, err)
+
+		router := gin.New()
+		register.JWKS(router, signatory)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, register.JWKSPath, nil)
+
+		assert.NoError(t, err)
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"keys":[]}`, w.Body.String())
+	})
+
+	t.Run("Matching ETags are not modified", func(t *testing.T) {
+		t.Parallel()
+
+		signatory, err := tonic.NewSignatory(config.Security{
+			PrivateKey: "../testdata/rsa.key",
+		})
+
+		assert.NoError(t, err)
+
+		router := gin.New()
+		register.JWKS(router, signatory)
+
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodGet, register.JWKSPath, nil)
+
+		assert.NoError(t, err)
+
+		router.ServeHTTP(w, req)
+
+		req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+}
@@ -0,0 +1,408 @@
+package register
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// A TokenIssuer issues and validates tokens. A tonic.Signatory is a
+// TokenIssuer.
+type TokenIssuer interface {
+	// Issue a token for the given claims.
+	Issue(claims jwt.MapClaims) (string, error)
+
+	// Parse and validate a token, returning its claims.
+	Parse(token string) (jwt.MapClaims, error)
+
+	// Lifetime of the issued tokens.
+	Lifetime() time.Duration
+}
+
+// A RefreshIssuer issues and rotates refresh tokens. Refresh tokens belong to a
+// family, which starts when the first token is issued, and each token can only
+// be rotated once. Rotating a token that has already been rotated means it was
+// stolen or replayed, so the whole family must be revoked. A jwt.Refresher is a
+// RefreshIssuer.
+type RefreshIssuer interface {
+	// Issue the first refresh token in a new family for the given claims.
+	Issue(claims jwt.MapClaims) (string, error)
+
+	// Parse and validate a refresh token, returning its claims.
+	Parse(token string) (jwt.MapClaims, error)
+
+	// Rotate the refresh token, atomically using it up and returning the next
+	// token in its family for the given claims.
+	Rotate(token string, claims jwt.MapClaims) (string, error)
+}
+
+// A Client is allowed to request tokens from the token endpoint.
+type Client struct {
+	// ID of the client. The ID is used as the subject of the issued tokens.
+	ID string `json:"id"`
+
+	// Secret is the bcrypt hash of the client secret, as returned by
+	// HashSecret.
+	Secret string `json:"secret"`
+
+	// Scopes the client may request. Clients that don't ask for specific
+	// scopes are given all of them.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Claims added to the tokens issued to the client.
+	Claims map[string]any `json:"claims,omitempty"`
+
+	// Refresh allows the client to be issued refresh tokens.
+	Refresh bool `json:"refresh,omitempty"`
+
+	// RevokeAny allows the client to revoke tokens that were not issued to a
+	// client, such as those issued by jwt.Sign. Tokens issued to other
+	// clients can never be revoked.
+	RevokeAny bool `json:"revokeAny,omitempty"`
+}
+
+// A ClientRegistry holds the clients allowed to use the token endpoint.
+type ClientRegistry interface {
+	// Lookup the client with the given ID.
+	Lookup(id string) (Client, bool)
+}
+
+// Clients is a ClientRegistry held in memory, keyed on the client ID.
+type Clients map[string]Client
+
+// TokenResponse is sent by the token endpoint, as defined in RFC 6749. It is
+// also sent by jwt.Pair and jwt.Refresh as jwt.Tokens.
+//
+//nolint:tagliatelle // Field names are defined by RFC 6749.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// TokenPath is the path the token endpoint is registered on.
+const TokenPath = "/oauth/token"
+
+// OAuth 2.0 grant types supported by the token endpoint.
+const (
+	ClientCredentialsGrant = "client_credentials"
+	RefreshTokenGrant      = "refresh_token"
+)
+
+// ClientIDClaim holds the ID of the client a token was issued to, as defined in
+// RFC 9068.
+const ClientIDClaim = "client_id"
+
+// refreshTokenParam holds the refresh token sent to the token endpoint.
+const refreshTokenParam = "refresh_token"
+
+// unknownClient is a bcrypt hash that secrets from unknown clients are compared
+// against, so they take as long to reject as known clients.
+const unknownClient = "$2a$10$OpbyqNWXKc3uYOUKTvD6juds6pmNZD9VCQJUgx5dasWbTTFI7xtyW"
+
+// RFC 6749 error codes.
+const (
+	invalidRequest       = "invalid_request"
+	invalidClient        = "invalid_client"
+	invalidGrant         = "invalid_grant"
+	invalidScope         = "invalid_scope"
+	unauthorizedClient   = "unauthorized_client"
+	unsupportedGrantType = "unsupported_grant_type"
+	serverError          = "server_error"
+)
+
+// Claims set on issued tokens. These match tonic.SubjectClaim and
+// tonic.ScopeClaim, which can't be imported here.
+const (
+	subjectClaim = "sub"
+	scopeClaim   = "scope"
+)
+
+// NewClients returns Clients holding the given clients.
+func NewClients(clients ...Client) Clients {
+	registry := make(Clients, len(clients))
+
+	for _, client := range clients {
+		registry[client.ID] = client
+	}
+
+	return registry
+}
+
+// Lookup the client with the given ID.
+func (c Clients) Lookup(id string) (Client, bool) {
+	client, ok := c[id]
+
+	return client, ok
+}
+
+// HashSecret returns the bcrypt hash of a client secret, as held in a Client.
+// Secrets longer than 72 bytes cannot be hashed.
+func HashSecret(secret string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to hash client secret: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+// TokenEndpoint registers an OAuth 2.0 token endpoint (RFC 6749) supporting the
+// client credentials and refresh token grants. Clients authenticate using HTTP
+// Basic authentication, or the client_id and client_secret form fields, and
+// are issued access tokens by the access TokenIssuer, typically created using
+// jwt.AccessIssuer. The tokens carry the client ID as the subject, the granted
+// scopes, and the client's claims.
+//
+// Clients allowed refresh tokens are issued them by the RefreshIssuer, typically
+// created using jwt.RefreshIssuer. Refresh tokens are bound to the client they
+// were issued to, and can be exchanged for a new access and refresh token with
+// the same, or fewer, scopes. Each refresh token can only be used once. Reusing
+// a refresh token revokes every refresh token descended from the same client
+// credentials grant. A nil RefreshIssuer disables refresh tokens.
+func TokenEndpoint(r *gin.Engine, clients ClientRegistry, access TokenIssuer, refresh RefreshIssuer) {
+	r.POST(TokenPath, func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.Header("Pragma", "no-cache")
+
+		client, ok := authenticateClient(c, clients)
+
+		if !ok {
+			return
+		}
+
+		var (
+			claims  jwt.MapClaims
+			rotated string
+			issue   func(jwt.MapClaims) (string, error)
+		)
+
+		switch grant := c.PostForm("grant_type"); grant {
+		case ClientCredentialsGrant:
+			claims, ok = credentialsGrant(c, client)
+
+			if refresh != nil && client.Refresh {
+				issue = refresh.Issue
+			}
+		case RefreshTokenGrant:
+			claims, rotated, ok = refreshGrant(c, client, refresh)
+			issue = func(jwt.MapClaims) (string, error) { return rotated, nil }
+		case "":
+			ok = oauthError(c, http.StatusBadRequest, invalidRequest, "grant_type is required")
+		default:
+			ok = oauthError(c, http.StatusBadRequest, unsupportedGrantType, grant+" is not supported")
+		}
+
+		if !ok {
+			return
+		}
+
+		response, err := issueTokens(claims, access, issue)
+
+		if err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(err)
+			oauthError(c, http.StatusInternalServerError, serverError, "failed to issue token")
+
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	})
+}
+
+// authenticateClient returns the client making the request. If the client
+// cannot be authenticated an error response is sent.
+func authenticateClient(c *gin.Context, clients ClientRegistry) (Client, bool) {
+	id, secret, basic := c.Request.BasicAuth()
+
+	if basic {
+		// RFC 6749 requires the credentials to be form encoded.
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+
+	client, known := clients.Lookup(id)
+	hash := client.Secret
+
+	// Secrets are always compared so unknown clients take as long to reject.
+	if !known {
+		hash = unknownClient
+	}
+
+	valid := bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)) == nil
+
+	if id != "" && known && valid {
+		return client, true
+	}
+
+	if basic {
+		c.Header("WWW-Authenticate", `Basic realm="token"`)
+	}
+
+	return client, oauthError(c, http.StatusUnauthorized, invalidClient, "client authentication failed")
+}
+
+// credentialsGrant returns the claims for a client credentials grant. If the
+// grant is invalid an error response is sent.
+func credentialsGrant(c *gin.Context, client Client) (jwt.MapClaims, bool) {
+	scopes, ok := grantScopes(c.PostForm("scope"), client.Scopes)
+
+	if !ok {
+		return nil, oauthError(c, http.StatusBadRequest, invalidScope, "scope is not allowed")
+	}
+
+	return clientClaims(client, scopes), true
+}
+
+// refreshGrant returns the claims for a refresh token grant, along with the
+// rotated refresh token. If the grant is invalid an error response is sent.
+func refreshGrant(c *gin.Context, client Client, refresh RefreshIssuer) (jwt.MapClaims, string, bool) {
+	if refresh == nil || !client.Refresh {
+		return nil, "", oauthError(c, http.StatusBadRequest, unauthorizedClient,
+			"client cannot use refresh tokens")
+	}
+
+	token := c.PostForm(refreshTokenParam)
+
+	if token == "" {
+		return nil, "", oauthError(c, http.StatusBadRequest, invalidRequest, "refresh_token is required")
+	}
+
+	claims, err := refresh.Parse(token)
+
+	if err != nil {
+		//nolint:errcheck // Gin is handling this for us.
+		_ = c.Error(err)
+
+		return nil, "", oauthError(c, http.StatusBadRequest, invalidGrant, "invalid refresh token")
+	}
+
+	if claims[ClientIDClaim] != client.ID {
+		return nil, "", oauthError(c, http.StatusBadRequest, invalidGrant,
+			"refresh token was issued to another client")
+	}
+
+	// Scopes are limited to those originally granted that the client is still
+	// allowed.
+	granted, _ := claims[scopeClaim].(string)
+	scopes, ok := grantScopes(c.PostForm("scope"), retainScopes(granted, client.Scopes))
+
+	if !ok {
+		return nil, "", oauthError(c, http.StatusBadRequest, invalidScope, "scope is not allowed")
+	}
+
+	claims = clientClaims(client, scopes)
+	rotated, err := refresh.Rotate(token, claims)
+
+	if err != nil {
+		//nolint:errcheck // Gin is handling this for us.
+		_ = c.Error(err)
+
+		return nil, "", oauthError(c, http.StatusBadRequest, invalidGrant, "refresh token has been used")
+	}
+
+	return claims, rotated, true
+}
+
+// issueTokens issues an access token for the claims, and a refresh token if
+// issue is not nil.
+func issueTokens(claims jwt.MapClaims, access TokenIssuer,
+	issue func(jwt.MapClaims) (string, error)) (TokenResponse, error) {
+	token, err := access.Issue(claims)
+
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	scope, _ := claims[scopeClaim].(string)
+	response := TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(access.Lifetime().Round(time.Second).Seconds()),
+		Scope:       scope,
+	}
+
+	if issue != nil {
+		response.RefreshToken, err = issue(claims)
+	}
+
+	return response, err
+}
+
+// clientClaims returns the claims for tokens issued to the client with the
+// given scopes.
+func clientClaims(client Client, scopes []string) jwt.MapClaims {
+	claims := jwt.MapClaims{}
+
+	for k, v := range client.Claims {
+		claims[k] = v
+	}
+
+	claims[subjectClaim] = client.ID
+	claims[ClientIDClaim] = client.ID
+
+	if len(scopes) > 0 {
+		claims[scopeClaim] = strings.Join(scopes, " ")
+	}
+
+	return claims
+}
+
+// retainScopes returns the space separated granted scopes that are still
+// allowed. No scopes are retained if none were granted.
+func retainScopes(granted string, allowed []string) []string {
+	var scopes []string
+
+	for _, scope := range strings.Fields(granted) {
+		if _, ok := grantScopes(scope, allowed); ok {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes
+}
+
+// grantScopes returns the requested space separated scopes if they are all
+// allowed, or all of the allowed scopes if none were requested.
+func grantScopes(requested string, allowed []string) ([]string, bool) {
+	scopes := strings.Fields(requested)
+
+	if len(scopes) == 0 {
+		return allowed, true
+	}
+
+	for _, scope := range scopes {
+		found := false
+
+		for _, a := range allowed {
+			found = found || a == scope
+		}
+
+		if !found {
+			return nil, false
+		}
+	}
+
+	return scopes, true
+}
+
+// oauthError sends an RFC 6749 error response. It always returns false so it
+// can be used to fail a grant.
+func oauthError(c *gin.Context, status int, code, description string) bool {
+	c.Abort()
+	c.JSON(status, gin.H{"error": code, "error_description": description})
+
+	return false
+}
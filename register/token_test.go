@@ -0,0 +1,384 @@
+package register_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/jwt"
+	"github.com/domdavis/tonic/register"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// Hash returns the hash of a client secret, panicking if it cannot be hashed.
+func Hash(secret string) string {
+	hash, err := register.HashSecret(secret)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return hash
+}
+
+// Post the form to the path, authenticating with the given client ID and
+// secret using HTTP Basic authentication.
+func Post(router *gin.Engine, path, id, secret string, form url.Values) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if id != "" {
+		req.SetBasicAuth(id, secret)
+	}
+
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+// Grant posts the form to the token endpoint, returning the response.
+func Grant(router *gin.Engine, id, secret string, form url.Values) (*httptest.ResponseRecorder,
+	register.TokenResponse) {
+	var response register.TokenResponse
+
+	w := Post(router, register.TokenPath, id, secret, form)
+
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+
+	return w, response
+}
+
+func ExampleTokenEndpoint() {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour, RefreshTTL: time.Hour * 24}
+	access, _ := jwt.AccessIssuer(security, nil)
+	refresh, _ := jwt.RefreshIssuer(security, jwt.NewMemoryStore(), nil)
+	secret, _ := register.HashSecret("s3cr3t")
+	clients := register.NewClients(register.Client{
+		ID:     "reporting",
+		Secret: secret,
+		Scopes: []string{"reports:read", "reports:write"},
+	})
+
+	router := gin.New()
+	register.TokenEndpoint(router, clients, access, refresh)
+
+	w, response := Grant(router, "reporting", "s3cr3t", url.Values{
+		"grant_type": {register.ClientCredentialsGrant},
+		"scope":      {"reports:read"},
+	})
+
+	claims, err := access.Parse(response.AccessToken)
+
+	fmt.Println(w.Code, w.Header().Get("Cache-Control"))
+	fmt.Println(response.TokenType, response.ExpiresIn, response.Scope, response.RefreshToken == "")
+	fmt.Println(claims[tonic.SubjectClaim], claims[tonic.ScopeClaim], err)
+
+	// Output:
+	// 200 no-store
+	// Bearer 3600 reports:read true
+	// reporting reports:read <nil>
+}
+
+func TestTokenEndpoint(t *testing.T) {
+	t.Parallel()
+
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour, RefreshTTL: time.Hour * 24}
+	access, err := jwt.AccessIssuer(security, nil)
+
+	assert.NoError(t, err)
+
+	refresh, err := jwt.RefreshIssuer(security, jwt.NewMemoryStore(), nil)
+
+	assert.NoError(t, err)
+
+	clients := register.NewClients(register.Client{
+		ID:      "client",
+		Secret:  Hash("secret"),
+		Scopes:  []string{"read", "write"},
+		Claims:  map[string]any{"tenant": "acme"},
+		Refresh: true,
+	}, register.Client{
+		ID:      "other",
+		Secret:  Hash("other"),
+		Scopes:  []string{"read"},
+		Refresh: true,
+	}, register.Client{
+		ID:     "service",
+		Secret: Hash("service"),
+	})
+
+	router := gin.New()
+	register.TokenEndpoint(router, clients, access, refresh)
+
+	t.Run("Clients can authenticate in the form", func(t *testing.T) {
+		t.Parallel()
+
+		w, response := Grant(router, "", "", url.Values{
+			"grant_type":    {register.ClientCredentialsGrant},
+			"client_id":     {"client"},
+			"client_secret": {"secret"},
+		})
+
+		claims, err := access.Parse(response.AccessToken)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, err)
+		assert.Equal(t, "read write", response.Scope)
+		assert.Equal(t, "acme", claims["tenant"])
+		assert.Equal(t, "client", claims[register.ClientIDClaim])
+		assert.NotEmpty(t, response.RefreshToken)
+	})
+
+	t.Run("Clients must authenticate", func(t *testing.T) {
+		t.Parallel()
+
+		for name, credentials := range map[string][2]string{
+			"wrong secret":   {"client", "wrong"},
+			"unknown client": {"unknown", "secret"},
+			"other secret":   {"client", "other"},
+		} {
+			w, _ := Grant(router, credentials[0], credentials[1], url.Values{
+				"grant_type": {register.ClientCredentialsGrant},
+			})
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code, name)
+			assert.Contains(t, w.Body.String(), "invalid_client", name)
+			assert.Equal(t, `Basic realm="token"`, w.Header().Get("WWW-Authenticate"), name)
+		}
+
+		w, _ := Grant(router, "", "", url.Values{"grant_type": {register.ClientCredentialsGrant}})
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("Invalid grants are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		for name, tc := range map[string]struct {
+			form url.Values
+			code string
+		}{
+			"missing grant type": {form: url.Values{}, code: "invalid_request"},
+			"unsupported grant":  {form: url.Values{"grant_type": {"password"}}, code: "unsupported_grant_type"},
+			"invalid scope": {
+				form: url.Values{"grant_type": {register.ClientCredentialsGrant}, "scope": {"read admin"}},
+				code: "invalid_scope",
+			},
+			"missing refresh token": {
+				form: url.Values{"grant_type": {register.RefreshTokenGrant}},
+				code: "invalid_request",
+			},
+			"invalid refresh token": {
+				form: url.Values{"grant_type": {register.RefreshTokenGrant}, "refresh_token": {"invalid"}},
+				code: "invalid_grant",
+			},
+		} {
+			w, _ := Grant(router, "client", "secret", tc.form)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, name)
+			assert.Contains(t, w.Body.String(), tc.code, name)
+		}
+	})
+
+	t.Run("Refresh tokens can be exchanged for access tokens", func(t *testing.T) {
+		t.Parallel()
+
+		_, issued := Grant(router, "client", "secret", url.Values{
+			"grant_type": {register.ClientCredentialsGrant},
+			"scope":      {"read write"},
+		})
+
+		w, response := Grant(router, "client", "secret", url.Values{
+			"grant_type":    {register.RefreshTokenGrant},
+			"refresh_token": {issued.RefreshToken},
+			"scope":         {"read"},
+		})
+
+		claims, err := access.Parse(response.AccessToken)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, err)
+		assert.Equal(t, "read", claims[tonic.ScopeClaim])
+		assert.NotEmpty(t, response.RefreshToken)
+
+		w, rotated := Grant(router, "client", "secret", url.Values{
+			"grant_type":    {register.RefreshTokenGrant},
+			"refresh_token": {response.RefreshToken},
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "read", rotated.Scope)
+		assert.NotEmpty(t, rotated.RefreshToken)
+
+		// Refresh tokens cannot be used as access tokens, or access tokens as
+		// refresh tokens.
+		_, err = access.Parse(issued.RefreshToken)
+
+		assert.ErrorIs(t, err, tonic.ErrWrongType)
+
+		w, _ = Grant(router, "client", "secret", url.Values{
+			"grant_type":    {register.RefreshTokenGrant},
+			"refresh_token": {issued.AccessToken},
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Refresh tokens cannot widen the scope", func(t *testing.T) {
+		t.Parallel()
+
+		_, issued := Grant(router, "client", "secret", url.Values{
+			"grant_type": {register.ClientCredentialsGrant},
+			"scope":      {"read"},
+		})
+
+		w, _ := Grant(router, "client", "secret", url.Values{
+			"grant_type":    {register.RefreshTokenGrant},
+			"refresh_token": {issued.RefreshToken},
+			"scope":         {"read write"},
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_scope")
+	})
+
+	t.Run("Refresh tokens are bound to their client", func(t *testing.T) {
+		t.Parallel()
+
+		_, issued := Grant(router, "client", "secret", url.Values{
+			"grant_type": {register.ClientCredentialsGrant},
+		})
+
+		w, _ := Grant(router, "other", "other", url.Values{
+			"grant_type":    {register.RefreshTokenGrant},
+			"refresh_token": {issued.RefreshToken},
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_grant")
+	})
+
+	t.Run("Only allowed clients are issued refresh tokens", func(t *testing.T) {
+		t.Parallel()
+
+		w, response := Grant(router, "service", "service", url.Values{
+			"grant_type": {register.ClientCredentialsGrant},
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, response.RefreshToken)
+		assert.Empty(t, response.Scope)
+
+		_, issued := Grant(router, "client", "secret", url.Values{
+			"grant_type": {register.ClientCredentialsGrant},
+		})
+
+		w, _ = Grant(router, "service", "service", url.Values{
+			"grant_type":    {register.RefreshTokenGrant},
+			"refresh_token": {issued.RefreshToken},
+		})
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "unauthorized_client")
+	})
+
+	t.Run("Reusing a refresh token revokes its family", func(t *testing.T) {
+		t.Parallel()
+
+		_, issued := Grant(router, "client", "secret", url.Values{
+			"grant_type": {register.ClientCredentialsGrant},
+		})
+
+		refreshed := func(token string) *httptest.ResponseRecorder {
+			w, _ := Grant(router, "client", "secret", url.Values{
+				"grant_type":    {register.RefreshTokenGrant},
+				"refresh_token": {token},
+			})
+
+			return w
+		}
+
+		w, rotated := Grant(router, "client", "secret", url.Values{
+			"grant_type":    {register.RefreshTokenGrant},
+			"refresh_token": {issued.RefreshToken},
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = refreshed(issued.RefreshToken)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_grant")
+		assert.Equal(t, http.StatusBadRequest, refreshed(rotated.RefreshToken).Code)
+	})
+
+	t.Run("Refresh tokens can only be used once at the same time", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			wg sync.WaitGroup
+			ok int32
+		)
+
+		_, issued := Grant(router, "client", "secret", url.Values{
+			"grant_type": {register.ClientCredentialsGrant},
+		})
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				w, _ := Grant(router, "client", "secret", url.Values{
+					"grant_type":    {register.RefreshTokenGrant},
+					"refresh_token": {issued.RefreshToken},
+				})
+
+				if w.Code == http.StatusOK {
+					atomic.AddInt32(&ok, 1)
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		assert.Equal(t, int32(1), atomic.LoadInt32(&ok))
+	})
+
+	t.Run("Refresh tokens without scopes are not given new scopes", func(t *testing.T) {
+		t.Parallel()
+
+		client := register.Client{ID: "growing", Secret: Hash("growing"), Refresh: true}
+		before := gin.New()
+		register.TokenEndpoint(before, register.NewClients(client), access, refresh)
+
+		_, issued := Grant(before, "growing", "growing", url.Values{
+			"grant_type": {register.ClientCredentialsGrant},
+		})
+
+		client.Scopes = []string{"read", "write"}
+		after := gin.New()
+		register.TokenEndpoint(after, register.NewClients(client), access, refresh)
+
+		w, response := Grant(after, "growing", "growing", url.Values{
+			"grant_type":    {register.RefreshTokenGrant},
+			"refresh_token": {issued.RefreshToken},
+		})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, response.Scope)
+	})
+}
@@ -0,0 +1,324 @@
+package tonic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// A RevocationStore records tokens that have been revoked before they expired,
+// such as when a user logs out. Tokens are revoked individually using their
+// jti claim, or for a subject as a whole by incrementing the subject's token
+// generation. Tokens for a subject carry the generation they were issued in
+// and are rejected once the generation has moved on.
+type RevocationStore interface {
+	// Revoke the token with the given ID. The store only needs to remember
+	// the token until it expires.
+	Revoke(id string, expires time.Time) error
+
+	// Revoked returns true if the token with the given ID has been revoked.
+	Revoked(id string) (bool, error)
+
+	// RevokeAll tokens issued to the given subject by incrementing the
+	// subject's generation.
+	RevokeAll(subject string) error
+
+	// Generation returns the current generation for the given subject.
+	// Subjects that have never been revoked are in generation 0.
+	Generation(subject string) (int64, error)
+}
+
+// Revocation errors.
+var (
+	ErrRevoked           = errors.New("token has been revoked")
+	ErrNoRevocationStore = errors.New("no revocation store")
+)
+
+// GenerationClaim holds the subject's token generation when the token was
+// signed.
+const GenerationClaim = "gen"
+
+// RevocationsKey is the context key used to hold the RevocationStore set by
+// Revocable.
+const RevocationsKey = "tonic.revocations"
+
+// MemoryRevocations is a RevocationStore held in memory. Revoked token IDs are
+// removed once the token has expired, with expired IDs being swept up each time
+// a token is revoked. A MemoryRevocations is safe for concurrent use, but
+// cannot be shared between instances of a service.
+type MemoryRevocations struct {
+	mu          sync.RWMutex
+	revoked     map[string]time.Time
+	generations map[string]int64
+}
+
+// FileRevocations is a RevocationStore that is persisted to a JSON file so
+// revocations survive restarts. The file is rewritten on every change. A
+// FileRevocations is safe for concurrent use, but the file should not be shared
+// between running services.
+type FileRevocations struct {
+	memory *MemoryRevocations
+	path   string
+}
+
+// revocations is the file format used by FileRevocations.
+type revocations struct {
+	Revoked     map[string]time.Time `json:"revoked"`
+	Generations map[string]int64     `json:"generations"`
+}
+
+const revocationsMode = 0o600
+
+// NewMemoryRevocations returns an empty MemoryRevocations.
+func NewMemoryRevocations() *MemoryRevocations {
+	return &MemoryRevocations{
+		revoked:     map[string]time.Time{},
+		generations: map[string]int64{},
+	}
+}
+
+// Revoke the token with the given ID until it expires.
+func (m *MemoryRevocations) Revoke(id string, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep()
+	m.revoked[id] = expires
+
+	return nil
+}
+
+// Revoked returns true if the token with the given ID has been revoked and has
+// not yet expired.
+func (m *MemoryRevocations) Revoked(id string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	expires, ok := m.revoked[id]
+
+	return ok && time.Now().Before(expires), nil
+}
+
+// RevokeAll tokens issued to the given subject.
+func (m *MemoryRevocations) RevokeAll(subject string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.generations[subject]++
+
+	return nil
+}
+
+// Generation returns the current generation for the given subject.
+func (m *MemoryRevocations) Generation(subject string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.generations[subject], nil
+}
+
+// sweep removes expired tokens. The lock must be held by the caller.
+func (m *MemoryRevocations) sweep() {
+	now := time.Now()
+
+	for id, expires := range m.revoked {
+		if now.After(expires) {
+			delete(m.revoked, id)
+		}
+	}
+}
+
+// NewFileRevocations returns a FileRevocations persisted to the given path. Any
+// existing revocations in the file are loaded. The file will be created on
+// the first change if it doesn't exist.
+func NewFileRevocations(path string) (*FileRevocations, error) {
+	f := &FileRevocations{memory: NewMemoryRevocations(), path: path}
+	b, err := os.ReadFile(path)
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return f, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read revocations: %w", err)
+	}
+
+	var stored revocations
+
+	if err = json.Unmarshal(b, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse revocations %s: %w", path, err)
+	}
+
+	for id, expires := range stored.Revoked {
+		f.memory.revoked[id] = expires
+	}
+
+	for subject, generation := range stored.Generations {
+		f.memory.generations[subject] = generation
+	}
+
+	f.memory.sweep()
+
+	return f, nil
+}
+
+// Revoke the token with the given ID until it expires.
+func (f *FileRevocations) Revoke(id string, expires time.Time) error {
+	f.memory.mu.Lock()
+	defer f.memory.mu.Unlock()
+
+	f.memory.sweep()
+	f.memory.revoked[id] = expires
+
+	return f.save()
+}
+
+// Revoked returns true if the token with the given ID has been revoked.
+func (f *FileRevocations) Revoked(id string) (bool, error) {
+	return f.memory.Revoked(id)
+}
+
+// RevokeAll tokens issued to the given subject.
+func (f *FileRevocations) RevokeAll(subject string) error {
+	f.memory.mu.Lock()
+	defer f.memory.mu.Unlock()
+
+	f.memory.generations[subject]++
+
+	return f.save()
+}
+
+// Generation returns the current generation for the given subject.
+func (f *FileRevocations) Generation(subject string) (int64, error) {
+	return f.memory.Generation(subject)
+}
+
+// save the revocations to the file, replacing it atomically. The memory lock
+// must be held by the caller.
+func (f *FileRevocations) save() error {
+	b, _ := json.Marshal(revocations{
+		Revoked:     f.memory.revoked,
+		Generations: f.memory.generations,
+	})
+
+	if err := WriteFileAtomic(f.path, b, revocationsMode); err != nil {
+		return fmt.Errorf("failed to save revocations: %w", err)
+	}
+
+	return nil
+}
+
+// Revocable returns middleware that sets the given RevocationStore on the
+// context. The cookie and jwt packages will use the store to revoke tokens on
+// logout and reject revoked tokens when authenticating. Revocable must be used
+// before any authentication middleware.
+func Revocable(store RevocationStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(RevocationsKey, store)
+		c.Next()
+	}
+}
+
+// Revocations returns the RevocationStore set on the context by Revocable, or
+// nil if no store has been set.
+//
+//nolint:ireturn // The store is whatever was set by Revocable.
+func Revocations(c *gin.Context) RevocationStore {
+	return Get[RevocationStore](c, RevocationsKey)
+}
+
+// Revoke a valid token so it can no longer be used. Revoke requires the
+// Signatory to have a RevocationStore.
+func (s *Signatory) Revoke(tokenString string) error {
+	if s.Revocations == nil {
+		return ErrNoRevocationStore
+	}
+
+	claims, err := s.Parse(tokenString)
+
+	if err != nil {
+		return err
+	}
+
+	id, _ := claims[IDClaim].(string)
+	expires, _, _ := timeClaim(claims, ExpiryClaim)
+
+	if id == "" {
+		return fmt.Errorf("%w: %s is required", ErrInvalidClaim, IDClaim)
+	}
+
+	if err = s.Revocations.Revoke(id, expires.Add(s.Leeway)); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	return nil
+}
+
+// generation sets the subject's current token generation on a payload being
+// signed. Payloads without a subject, or signed without a RevocationStore, are
+// left alone.
+func (s *Signatory) generation(payload jwt.MapClaims) error {
+	subject, _ := payload[SubjectClaim].(string)
+
+	if s.Revocations == nil || subject == "" {
+		return nil
+	}
+
+	generation, err := s.Revocations.Generation(subject)
+
+	if err != nil {
+		return fmt.Errorf("failed to get token generation: %w", err)
+	}
+
+	payload[GenerationClaim] = generation
+
+	return nil
+}
+
+// verifyRevocation ensures the token has not been revoked, either directly or
+// by its subject's generation moving on.
+func (s *Signatory) verifyRevocation(claims jwt.MapClaims) error {
+	if s.Revocations == nil {
+		return nil
+	}
+
+	if id, _ := claims[IDClaim].(string); id != "" {
+		if revoked, err := s.Revocations.Revoked(id); err != nil {
+			return fmt.Errorf("failed to check revocation: %w", err)
+		} else if revoked {
+			return fmt.Errorf("%w: %s", ErrRevoked, id)
+		}
+	}
+
+	subject, _ := claims[SubjectClaim].(string)
+
+	if subject == "" {
+		return nil
+	}
+
+	current, err := s.Revocations.Generation(subject)
+
+	if err != nil {
+		return fmt.Errorf("failed to check revocation: %w", err)
+	}
+
+	var generation int64
+
+	switch v := claims[GenerationClaim].(type) {
+	case float64:
+		generation = int64(v)
+	case json.Number:
+		generation, _ = v.Int64()
+	}
+
+	if generation < current {
+		return fmt.Errorf("%w: generation %d for %s", ErrRevoked, generation, subject)
+	}
+
+	return nil
+}
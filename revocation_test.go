@@ -0,0 +1,185 @@
+package tonic_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleSignatory_Revoke() {
+	s := &tonic.Signatory{
+		Secret: "secret", TTL: time.Hour, Revocations: tonic.NewMemoryRevocations(),
+	}
+
+	token, _ := s.Issue(jwt.MapClaims{tonic.SubjectClaim: "user"})
+	other, _ := s.Issue(jwt.MapClaims{tonic.SubjectClaim: "user"})
+
+	// Log out of a single session.
+	fmt.Println(s.Revoke(token))
+	fmt.Println(errors.Is(s.Validate(&gin.Context{}, token), tonic.ErrRevoked))
+	fmt.Println(s.Validate(&gin.Context{}, other))
+
+	// Log out everywhere.
+	fmt.Println(s.Revocations.RevokeAll("user"))
+	fmt.Println(errors.Is(s.Validate(&gin.Context{}, other), tonic.ErrRevoked))
+
+	// New sessions are unaffected.
+	token, _ = s.Issue(jwt.MapClaims{tonic.SubjectClaim: "user"})
+	fmt.Println(s.Validate(&gin.Context{}, token))
+
+	// Output:
+	// <nil>
+	// true
+	// <nil>
+	// <nil>
+	// true
+	// <nil>
+}
+
+func TestSignatory_Revoke(t *testing.T) {
+	t.Run("A Signatory without a store cannot revoke", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		token, err := s.Issue(jwt.MapClaims{})
+
+		assert.NoError(t, err)
+		assert.ErrorIs(t, s.Revoke(token), tonic.ErrNoRevocationStore)
+	})
+
+	t.Run("Invalid tokens cannot be revoked", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{
+			Secret: "secret", TTL: time.Hour, Revocations: tonic.NewMemoryRevocations(),
+		}
+
+		assert.Error(t, s.Revoke("garbage"))
+	})
+
+	t.Run("Tokens without an ID cannot be revoked", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{
+			Secret: "secret", TTL: time.Hour, Revocations: tonic.NewMemoryRevocations(),
+		}
+
+		token := Mint(t, jwt.SigningMethodHS512, jwt.MapClaims{
+			tonic.ExpiryClaim: time.Now().Add(time.Hour).Unix(),
+		})
+
+		assert.ErrorIs(t, s.Revoke(token), tonic.ErrInvalidClaim)
+	})
+
+	t.Run("Tokens signed without a store are revoked by RevokeAll", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		token, err := s.Issue(jwt.MapClaims{tonic.SubjectClaim: "user"})
+
+		assert.NoError(t, err)
+
+		s.Revocations = tonic.NewMemoryRevocations()
+
+		assert.NoError(t, s.Validate(&gin.Context{}, token))
+		assert.NoError(t, s.Revocations.RevokeAll("user"))
+		assert.Error(t, s.Validate(&gin.Context{}, token))
+	})
+}
+
+func TestMemoryRevocations_Revoked(t *testing.T) {
+	t.Run("Expired revocations are forgotten", func(t *testing.T) {
+		t.Parallel()
+
+		store := tonic.NewMemoryRevocations()
+
+		assert.NoError(t, store.Revoke("expired", time.Now().Add(-time.Second)))
+		assert.NoError(t, store.Revoke("current", time.Now().Add(time.Hour)))
+
+		revoked, err := store.Revoked("expired")
+
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+
+		revoked, err = store.Revoked("current")
+
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+}
+
+func TestFileRevocations(t *testing.T) {
+	t.Run("Revocations are persisted", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "revocations.json")
+		store, err := tonic.NewFileRevocations(path)
+
+		assert.NoError(t, err)
+		assert.NoError(t, store.Revoke("id", time.Now().Add(time.Hour)))
+		assert.NoError(t, store.RevokeAll("user"))
+
+		store, err = tonic.NewFileRevocations(path)
+
+		assert.NoError(t, err)
+
+		revoked, err := store.Revoked("id")
+
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		generation, err := store.Generation("user")
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), generation)
+	})
+
+	t.Run("Invalid files will error", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "revocations.json")
+
+		assert.NoError(t, os.WriteFile(path, []byte("garbage"), 0o600))
+
+		_, err := tonic.NewFileRevocations(path)
+
+		assert.Error(t, err)
+
+		_, err = tonic.NewFileRevocations(t.TempDir())
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Unwritable files will error", func(t *testing.T) {
+		t.Parallel()
+
+		store, err := tonic.NewFileRevocations(filepath.Join(t.TempDir(), "missing", "file"))
+
+		assert.NoError(t, err)
+		assert.Error(t, store.Revoke("id", time.Now().Add(time.Hour)))
+		assert.Error(t, store.RevokeAll("user"))
+	})
+}
+
+func TestRevocable(t *testing.T) {
+	t.Run("The store is set on the context", func(t *testing.T) {
+		t.Parallel()
+
+		store := tonic.NewMemoryRevocations()
+		c := &gin.Context{}
+
+		assert.Nil(t, tonic.Revocations(c))
+
+		tonic.Revocable(store)(c)
+
+		assert.Equal(t, store, tonic.Revocations(c))
+	})
+}
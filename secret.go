@@ -0,0 +1,235 @@
+package tonic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/domdavis/tonic/config"
+)
+
+// A SecretProvider provides the secret used to sign and validate tokens. The
+// secret may change between calls, in which case a Signatory will rotate its
+// KeyRing so tokens signed with the previous secret remain valid.
+type SecretProvider interface {
+	Secret() (string, error)
+}
+
+// LiteralSecret provides a fixed secret.
+type LiteralSecret string
+
+// EnvSecret provides the secret held in the named environment variable.
+type EnvSecret string
+
+// FileSecret provides the secret held in the file at the given path, such as a
+// Docker or Kubernetes secret mounted as a file. The file is read each time the
+// secret is needed. Leading and trailing whitespace is removed.
+type FileSecret string
+
+// WatchedSecret provides the secret held in a file, in the same way as
+// FileSecret, but only reads the file when it has been modified. The file is
+// checked at most once every Interval. A WatchedSecret is safe for concurrent
+// use.
+type WatchedSecret struct {
+	// Path to the file holding the secret.
+	Path string
+
+	// Interval between checks for changes to the file.
+	Interval time.Duration
+
+	mu       sync.Mutex
+	secret   string
+	modified time.Time
+	checked  time.Time
+}
+
+// Secret provider errors.
+var (
+	ErrMissingSecret = errors.New("missing secret")
+	ErrRandomSecret  = errors.New("random secret used in release mode")
+)
+
+// DefaultWatchInterval is the Interval used by NewWatchedSecret.
+const DefaultWatchInterval = time.Second * 10
+
+// Secret returns the literal secret.
+func (l LiteralSecret) Secret() (string, error) {
+	if l == "" {
+		return "", ErrMissingSecret
+	}
+
+	return string(l), nil
+}
+
+// Secret returns the value of the environment variable.
+func (e EnvSecret) Secret() (string, error) {
+	secret := os.Getenv(string(e))
+
+	if secret == "" {
+		return "", fmt.Errorf("%w: %s is not set", ErrMissingSecret, string(e))
+	}
+
+	return secret, nil
+}
+
+// Secret returns the contents of the file.
+func (f FileSecret) Secret() (string, error) {
+	b, err := os.ReadFile(string(f))
+
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+
+	secret := strings.TrimSpace(string(b))
+
+	if secret == "" {
+		return "", fmt.Errorf("%w: %s is empty", ErrMissingSecret, string(f))
+	}
+
+	return secret, nil
+}
+
+// NewWatchedSecret returns a WatchedSecret for the file at the given path,
+// checking for changes every DefaultWatchInterval. An error is returned if the
+// secret cannot be read.
+func NewWatchedSecret(path string) (*WatchedSecret, error) {
+	w := &WatchedSecret{Path: path, Interval: DefaultWatchInterval}
+
+	if _, err := w.Secret(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Secret returns the contents of the file, reading it again if it has been
+// modified since it was last read. If the file cannot be read then the last
+// secret read is used.
+func (w *WatchedSecret) Secret() (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.secret != "" && time.Since(w.checked) < w.Interval {
+		return w.secret, nil
+	}
+
+	w.checked = time.Now()
+	info, err := os.Stat(w.Path)
+
+	switch {
+	case err != nil && w.secret != "":
+		return w.secret, nil
+	case err != nil:
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	case w.secret != "" && info.ModTime().Equal(w.modified):
+		return w.secret, nil
+	}
+
+	secret, err := FileSecret(w.Path).Secret()
+
+	switch {
+	case err != nil && w.secret != "":
+		return w.secret, nil
+	case err != nil:
+		return "", err
+	}
+
+	w.secret = secret
+	w.modified = info.ModTime()
+
+	return w.secret, nil
+}
+
+// refreshSecret rotates the KeyRing if the secret from the SecretProvider has
+// changed. Signatories using a private or public key are left alone. The
+// KeyRing must have been created by NewSignatory or Initialise, since one
+// created here would belong to the copy of the Signatory it was called on.
+func (s *Signatory) refreshSecret() error {
+	if s.Secrets == nil || s.PrivateKey != nil || s.PublicKey != nil {
+		return nil
+	}
+
+	if s.Keys == nil {
+		return fmt.Errorf("%w: secrets can only be rotated using a KeyRing", ErrMissingKey)
+	}
+
+	secret, err := s.Secrets.Secret()
+
+	if err != nil {
+		return fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	s.Keys.Activate(NewSecretKey(secret))
+
+	return nil
+}
+
+// DeriveKeys returns keys for the given purpose, such as signing CSRF tokens,
+// derived from the Signatory's secrets so they follow any rotation of the
+// secret. The first key is derived from the active secret and should be used
+// for signing. Any others are derived from retired secrets that are still in
+// their grace period, and can be used for verification. Signatories that sign
+// tokens with a private key derive a single key from the Secret, which must be
+// set explicitly since the random default secret differs between instances of
+// a service.
+func (s *Signatory) DeriveKeys(purpose string) ([][]byte, error) {
+	if err := s.refreshSecret(); err != nil {
+		return nil, err
+	}
+
+	active := s.activeKey()
+
+	if active == nil {
+		return nil, fmt.Errorf("%w: no active key", ErrMissingKey)
+	}
+
+	secret, ok := active.Signing.([]byte)
+
+	if !ok {
+		explicit, err := s.explicitSecret()
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive %s key: %w", purpose, err)
+		}
+
+		return [][]byte{derive([]byte(explicit), purpose)}, nil
+	}
+
+	keys := [][]byte{derive(secret, purpose)}
+
+	if s.Keys == nil {
+		return keys, nil
+	}
+
+	for _, key := range s.Keys.Keys() {
+		if retired, ok := key.Signing.([]byte); ok && key.ID != active.ID {
+			keys = append(keys, derive(retired, purpose))
+		}
+	}
+
+	return keys, nil
+}
+
+// explicitSecret returns the Secret, or ErrMissingSecret if it's the random
+// default secret. This is used by Signatories that sign tokens with a private
+// key, where the KeyRing holds no secret.
+func (s *Signatory) explicitSecret() (string, error) {
+	if s.Secret == "" || s.Secret == config.RandomSecret || s.Secret == defaultSecret {
+		return "", fmt.Errorf("%w: a secret must be set when signing with a private key", ErrMissingSecret)
+	}
+
+	return s.Secret, nil
+}
+
+// derive a key for the given purpose from the secret.
+func derive(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,303 @@
+package tonic_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleWatchedSecret() {
+	dir, _ := os.MkdirTemp("", "secret")
+	path := filepath.Join(dir, "secret")
+
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	_ = os.WriteFile(path, []byte("old secret\n"), 0o600)
+
+	secrets, _ := tonic.NewWatchedSecret(path)
+	secrets.Interval = 0
+	s := &tonic.Signatory{TTL: time.Hour, Secrets: secrets}
+	token, _ := s.Sign(&gin.Context{})
+
+	// Rotate the secret, making sure the modification time changes.
+	_ = os.WriteFile(path, []byte("new secret\n"), 0o600)
+	_ = os.Chtimes(path, time.Now(), time.Now().Add(time.Minute))
+
+	fmt.Println(s.Validate(&gin.Context{}, token))
+	fmt.Println(len(s.Keys.Keys()))
+
+	// Output:
+	// <nil>
+	// 2
+}
+
+func TestLiteralSecret_Secret(t *testing.T) {
+	t.Run("Empty secrets will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.LiteralSecret("").Secret()
+
+		assert.ErrorIs(t, err, tonic.ErrMissingSecret)
+
+		secret, err := tonic.LiteralSecret("secret").Secret()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", secret)
+	})
+}
+
+//nolint:paralleltest // t.Setenv cannot be used in parallel tests.
+func TestEnvSecret_Secret(t *testing.T) {
+	t.Run("The secret is read from the environment", func(t *testing.T) {
+		t.Setenv("TONIC_TEST_SECRET", "secret")
+
+		secret, err := tonic.EnvSecret("TONIC_TEST_SECRET").Secret()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", secret)
+	})
+
+	t.Run("Unset variables will error", func(t *testing.T) {
+		_, err := tonic.EnvSecret("TONIC_TEST_UNSET_SECRET").Secret()
+
+		assert.ErrorIs(t, err, tonic.ErrMissingSecret)
+	})
+}
+
+func TestFileSecret_Secret(t *testing.T) {
+	t.Run("The secret is read from the file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "secret")
+
+		assert.NoError(t, os.WriteFile(path, []byte(" secret\n"), 0o600))
+
+		secret, err := tonic.FileSecret(path).Secret()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", secret)
+	})
+
+	t.Run("Missing and empty files will error", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "secret")
+
+		_, err := tonic.FileSecret(path).Secret()
+
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		assert.NoError(t, os.WriteFile(path, []byte("\n"), 0o600))
+
+		_, err = tonic.FileSecret(path).Secret()
+
+		assert.ErrorIs(t, err, tonic.ErrMissingSecret)
+	})
+}
+
+func TestWatchedSecret_Secret(t *testing.T) {
+	t.Run("Missing files will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.NewWatchedSecret(filepath.Join(t.TempDir(), "secret"))
+
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("The last secret is kept if the file is removed or emptied", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "secret")
+
+		assert.NoError(t, os.WriteFile(path, []byte("secret"), 0o600))
+
+		secrets, err := tonic.NewWatchedSecret(path)
+
+		assert.NoError(t, err)
+
+		secrets.Interval = 0
+
+		assert.NoError(t, os.WriteFile(path, []byte(""), 0o600))
+		assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+		secret, err := secrets.Secret()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", secret)
+		assert.NoError(t, os.Remove(path))
+
+		secret, err = secrets.Secret()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "secret", secret)
+	})
+
+	t.Run("Changes are not seen until the interval has passed", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "secret")
+
+		assert.NoError(t, os.WriteFile(path, []byte("old"), 0o600))
+
+		secrets, err := tonic.NewWatchedSecret(path)
+
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(path, []byte("new"), 0o600))
+		assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+		secret, err := secrets.Secret()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "old", secret)
+	})
+}
+
+func TestSignatory_Secrets(t *testing.T) {
+	t.Run("Provider errors stop signing and validation", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{TTL: time.Hour, Secrets: tonic.LiteralSecret("")}
+
+		_, err := s.Sign(&gin.Context{})
+
+		assert.ErrorIs(t, err, tonic.ErrMissingSecret)
+		assert.ErrorIs(t, s.Validate(&gin.Context{}, "token"), tonic.ErrMissingSecret)
+	})
+
+	t.Run("Copies share the KeyRing created by Initialise", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{TTL: time.Hour, Secrets: tonic.LiteralSecret("secret")}
+
+		assert.ErrorIs(t, s.Validate(&gin.Context{}, "token"), tonic.ErrMissingKey)
+
+		s.Initialise()
+		c := *s
+
+		token, err := c.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+		assert.Same(t, s.Keys, c.Keys)
+		assert.NoError(t, s.Validate(&gin.Context{}, token))
+	})
+
+	t.Run("Signatories can share a secret file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "secret")
+
+		assert.NoError(t, os.WriteFile(path, []byte("secret"), 0o600))
+
+		security := config.Security{SecretFile: path, SessionTTL: time.Hour}
+		signer, err := tonic.NewSignatory(security)
+
+		assert.NoError(t, err)
+
+		verifier, err := tonic.NewSignatory(security)
+
+		assert.NoError(t, err)
+
+		token, err := signer.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+		assert.NoError(t, verifier.Validate(&gin.Context{}, token))
+	})
+
+	t.Run("Missing secret files will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.NewSignatory(config.Security{
+			SecretFile: filepath.Join(t.TempDir(), "secret"),
+		})
+
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+//nolint:paralleltest // Changes the global Gin mode.
+func TestNewSignatory_strict(t *testing.T) {
+	t.Run("Random secrets will error in release mode", func(t *testing.T) {
+		mode := gin.Mode()
+		gin.SetMode(gin.ReleaseMode)
+
+		defer gin.SetMode(mode)
+
+		_, err := tonic.NewSignatory(config.Security{
+			Secret: config.RandomSecret, StrictSecret: true,
+		})
+
+		assert.ErrorIs(t, err, tonic.ErrRandomSecret)
+
+		_, err = tonic.NewSignatory(config.Security{
+			Secret: "secret", StrictSecret: true,
+		})
+
+		assert.NoError(t, err)
+
+		_, err = tonic.NewSignatory(config.Security{Secret: config.RandomSecret})
+
+		assert.NoError(t, err)
+	})
+}
+
+func ExampleSignatory_DeriveKeys() {
+	s := &tonic.Signatory{TTL: time.Hour, Keys: tonic.NewKeyRing(tonic.NewSecretKey("old"), time.Hour)}
+	before, _ := s.DeriveKeys("csrf")
+
+	s.Keys.Rotate(tonic.NewSecretKey("new"))
+
+	after, _ := s.DeriveKeys("csrf")
+
+	// Keys derived from the retired secret are still returned for verification.
+	fmt.Println(len(before), len(after), string(after[1]) == string(before[0]))
+
+	// Output:
+	// 1 2 true
+}
+
+func TestSignatory_DeriveKeys(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Keys differ by purpose", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		s.Initialise()
+
+		csrf, err := s.DeriveKeys("csrf")
+
+		assert.NoError(t, err)
+
+		session, err := s.DeriveKeys("session")
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, csrf, session)
+	})
+
+	t.Run("Signing keys need an explicit secret", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := tonic.LoadPrivateKey("testdata/ecdsa.key")
+
+		assert.NoError(t, err)
+
+		s := &tonic.Signatory{TTL: time.Hour, PrivateKey: key}
+		s.Initialise()
+
+		_, err = s.DeriveKeys("csrf")
+
+		assert.ErrorIs(t, err, tonic.ErrMissingSecret)
+
+		s.Secret = "secret"
+		keys, err := s.DeriveKeys("csrf")
+
+		assert.NoError(t, err)
+		assert.Len(t, keys, 1)
+	})
+}
@@ -0,0 +1,4 @@
+// Package session handles authentication with server side sessions. The
+// session cookie only holds a signed session ID, with the session data held in
+// a Store.
+package session
@@ -0,0 +1,430 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Session data held in a Store.
+type Session struct {
+	// ID of the session, carried by the session cookie.
+	ID string `json:"id"`
+
+	// Subject the session was started for.
+	Subject string `json:"subject,omitempty"`
+
+	// Expires is the time the session expires.
+	Expires time.Time `json:"expires"`
+
+	// Values held in the session, encoded as JSON. Use Get and Set to access
+	// the values in a type safe manner.
+	Values map[string]json.RawMessage `json:"values,omitempty"`
+}
+
+// Authenticator authenticates requests using the session named in the session
+// cookie. An Authenticator can be used with an auth.Chain, or using the
+// Authenticate middleware.
+type Authenticator struct {
+	security  config.Security
+	store     Store
+	signatory *tonic.Signatory
+	err       error
+}
+
+// Method is the authentication method used by an Authenticator.
+const Method = "session"
+
+// Name of the session cookie. The cookie is prefixed, and uses the path,
+// SameSite mode, and Secure settings from the Cookie settings in
+// config.Security, but the cookie Name setting only applies to the cookie
+// package.
+const Name = "GinAndTonicSession"
+
+// Context keys used to hold the current Session and the Store it came from.
+const (
+	Key      = "tonic.session"
+	StoreKey = "tonic.sessions"
+)
+
+// sessionPurpose is used to derive the keys that sign session IDs.
+const sessionPurpose = "session"
+
+// Session errors.
+var (
+	ErrNoSession      = errors.New("no session")
+	ErrInvalidSession = errors.New("invalid session cookie")
+)
+
+// Start a new session for the given subject, saving it in the store and
+// setting the session cookie. Any existing session for the request is deleted
+// so session IDs are never reused after logging in. The session is set on the
+// context so values can be set straight away.
+func Start(c *gin.Context, security config.Security, store Store, subject string) (*Session, error) {
+	maxAge := int(security.SessionTTL.Round(time.Second).Seconds())
+
+	if security.SessionTTL <= 0 || maxAge <= 0 {
+		return nil, fmt.Errorf("%w: %v", tonic.ErrInvalidTTL, security.SessionTTL)
+	}
+
+	keys, err := signingKeys(security)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+
+	if previous, invalid := current(c, security, keys); invalid == nil {
+		if err = store.Delete(previous); err != nil {
+			return nil, fmt.Errorf("failed to end previous session: %w", err)
+		}
+	}
+
+	s := &Session{
+		ID:      tonic.GenerateID(),
+		Subject: subject,
+		Expires: time.Now().Add(security.SessionTTL),
+	}
+
+	if err = store.Save(s); err != nil {
+		return nil, fmt.Errorf("failed to start session: %w", err)
+	}
+
+	http.SetCookie(c.Writer, bake(security, sign(keys[0], s.ID), maxAge))
+	set(c, store, s)
+
+	return s, nil
+}
+
+// End the current session, deleting it from the store and clearing the session
+// cookie. The cookie is cleared even if the session cannot be deleted.
+func End(c *gin.Context, security config.Security, store Store) error {
+	defer http.SetCookie(c.Writer, bake(security, "", -1))
+
+	keys, err := signingKeys(security)
+
+	if err != nil {
+		return fmt.Errorf("failed to end session: %w", err)
+	}
+
+	id, err := current(c, security, keys)
+
+	if err != nil {
+		return nil
+	}
+
+	c.Set(Key, (*Session)(nil))
+
+	if err = store.Delete(id); err != nil {
+		return fmt.Errorf("failed to end session: %w", err)
+	}
+
+	return nil
+}
+
+// NewAuthenticator returns an Authenticator loading sessions from the given
+// store.
+func NewAuthenticator(security config.Security, store Store) *Authenticator {
+	signatory, err := sessions(security)
+
+	if err != nil {
+		err = fmt.Errorf("%w: %w", tonic.ErrMisconfigured, err)
+	}
+
+	return &Authenticator{security: security, store: store, signatory: signatory, err: err}
+}
+
+// Method returns the authentication method used by the Authenticator.
+func (a *Authenticator) Method() string {
+	return Method
+}
+
+// Authenticate the request by loading the session named in the session cookie
+// from the store, setting it on the context. tonic.ErrMissingToken is returned
+// if the request doesn't have a session cookie, and tonic.ErrMisconfigured if
+// the security settings are invalid.
+//
+// The session ID is set on the context under cookie.SessionIDClaim, so the
+// session can be used with cookie.Protect, and the subject is set under
+// tonic.SubjectClaim. Both are set as the claims of the principal under
+// tonic.PrincipalKey.
+func (a *Authenticator) Authenticate(c *gin.Context) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	var (
+		s  *Session
+		id string
+	)
+
+	keys, err := a.signatory.DeriveKeys(sessionPurpose)
+
+	if err == nil {
+		id, err = current(c, a.security, keys)
+	}
+
+	if err == nil {
+		s, err = a.store.Load(id)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to authenticate session: %w", err)
+	}
+
+	set(c, a.store, s)
+
+	return nil
+}
+
+// Authenticate a request by loading the session named in the session cookie
+// from the store, in the same way as an Authenticator. Failure to authenticate
+// will abort the middleware chain and either redirect the request to the given
+// URL, or return http.StatusUnauthorized if the redirect is blank, in the same
+// way as cookie.Authenticate, including the next query parameter. If the
+// security settings are invalid then every request will fail with
+// http.StatusInternalServerError. The reason a session failed to authenticate
+// is passed to c.Error.
+func Authenticate(security config.Security, store Store, redirect string) gin.HandlerFunc {
+	a := NewAuthenticator(security, store)
+
+	return func(c *gin.Context) {
+		if a.err != nil {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(fmt.Errorf("failed to authenticate session: %w", a.err))
+			c.Abort()
+			c.String(http.StatusInternalServerError,
+				http.StatusText(http.StatusInternalServerError))
+
+			return
+		}
+
+		invalid := a.Authenticate(c)
+
+		// Redirecting visitors without a session is part of the normal login
+		// flow, so it's not reported as an error.
+		if invalid != nil && !(redirect != "" && errors.Is(invalid, tonic.ErrMissingToken)) {
+			//nolint:errcheck // Gin is handling this for us.
+			_ = c.Error(invalid)
+		}
+
+		switch {
+		case invalid != nil && redirect != "":
+			c.Abort()
+			c.Redirect(http.StatusTemporaryRedirect, cookie.ReturnTo(c, redirect))
+		case invalid != nil && redirect == "":
+			c.Abort()
+			c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		default:
+			c.Next()
+		}
+	}
+}
+
+// Current returns the session set on the context by Authenticate or Start, or
+// nil if there is no session.
+func Current(c *gin.Context) *Session {
+	return tonic.Get[*Session](c, Key)
+}
+
+// Get a value from the current session in a type safe manner. If there is no
+// session, or no value is associated with the key, then the zero value for the
+// type will be returned. If the value cannot be decoded into type T then Get
+// will panic.
+func Get[T any](c *gin.Context, key string) T {
+	var t T
+
+	s := Current(c)
+
+	if s == nil {
+		return t
+	}
+
+	raw, ok := s.Values[key]
+
+	if !ok {
+		return t
+	}
+
+	if err := json.Unmarshal(raw, &t); err != nil {
+		panic(fmt.Sprintf("cannot get %T from session, value is %s", t, raw))
+	}
+
+	return t
+}
+
+// Set a value in the current session, saving the session to the store. The
+// value must be able to be encoded as JSON. ErrNoSession is returned if there
+// is no current session.
+func Set(c *gin.Context, key string, value any) error {
+	s := Current(c)
+
+	if s == nil {
+		return ErrNoSession
+	}
+
+	raw, err := json.Marshal(value)
+
+	if err != nil {
+		return fmt.Errorf("failed to set %s: %w", key, err)
+	}
+
+	if s.Values == nil {
+		s.Values = map[string]json.RawMessage{}
+	}
+
+	s.Values[key] = raw
+
+	return save(c, s)
+}
+
+// Delete a value from the current session, saving the session to the store.
+// ErrNoSession is returned if there is no current session.
+func Delete(c *gin.Context, key string) error {
+	s := Current(c)
+
+	if s == nil {
+		return ErrNoSession
+	}
+
+	delete(s.Values, key)
+
+	return save(c, s)
+}
+
+// save the session to the store held in the context.
+func save(c *gin.Context, s *Session) error {
+	if err := tonic.Get[Store](c, StoreKey).Save(s); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return nil
+}
+
+// set the session, store, and principal on the context.
+func set(c *gin.Context, store Store, s *Session) {
+	principal := jwt.MapClaims{cookie.SessionIDClaim: s.ID}
+
+	c.Set(Key, s)
+	c.Set(StoreKey, store)
+	c.Set(cookie.SessionIDClaim, s.ID)
+
+	if s.Subject != "" {
+		principal[tonic.SubjectClaim] = s.Subject
+		c.Set(tonic.SubjectClaim, s.Subject)
+	}
+
+	c.Set(tonic.PrincipalKey, principal)
+}
+
+// current returns the session ID held in the session cookie, which must have
+// been signed with one of the keys.
+func current(c *gin.Context, security config.Security, keys [][]byte) (string, error) {
+	value, err := c.Cookie(security.Cookie.Prefixed(Name))
+
+	if err != nil {
+		return "", tonic.ErrMissingToken
+	}
+
+	id, _, ok := strings.Cut(value, ".")
+
+	if !ok {
+		return "", ErrInvalidSession
+	}
+
+	for _, key := range keys {
+		if hmac.Equal([]byte(sign(key, id)), []byte(value)) {
+			return id, nil
+		}
+	}
+
+	return "", ErrInvalidSession
+}
+
+// bake a session cookie holding the given value, using the Cookie settings.
+func bake(security config.Security, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     security.Cookie.Prefixed(Name),
+		Value:    value,
+		MaxAge:   maxAge,
+		Path:     security.Cookie.Scope(),
+		Domain:   security.Domain,
+		Secure:   security.SecureCookies(),
+		HttpOnly: true,
+		SameSite: security.Cookie.Mode(),
+	}
+}
+
+// sessions returns the shared Signatory for the security settings, which is
+// used to derive the keys that sign session IDs. An error is returned if the
+// security settings are invalid or keys cannot be derived from them.
+func sessions(security config.Security) (*tonic.Signatory, error) {
+	if err := security.Cookie.Validate(security.Domain, security.SecureCookies()); err != nil {
+		return nil, err
+	}
+
+	signatory, err := tonic.SharedSignatory(security)
+
+	if err == nil {
+		_, err = signatory.DeriveKeys(sessionPurpose)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid security settings: %w", err)
+	}
+
+	return signatory, nil
+}
+
+// signingKeys returns the keys used to sign session IDs. The first key is used
+// for signing, the rest are derived from retired secrets.
+func signingKeys(security config.Security) ([][]byte, error) {
+	signatory, err := sessions(security)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := signatory.DeriveKeys(sessionPurpose)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive session keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// sign the session ID for use in the session cookie.
+func sign(key []byte, id string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id))
+
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// clone returns a deep copy of the session so stores don't share values with
+// their callers.
+func (s *Session) clone() *Session {
+	clone := *s
+
+	if s.Values != nil {
+		clone.Values = make(map[string]json.RawMessage, len(s.Values))
+
+		for k, v := range s.Values {
+			clone.Values[k] = v
+		}
+	}
+
+	return &clone
+}
@@ -0,0 +1,387 @@
+package session_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/auth"
+	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/cookie"
+	"github.com/domdavis/tonic/register"
+	"github.com/domdavis/tonic/session"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type Basket struct {
+	Items []string `json:"items"`
+}
+
+func Router(t *testing.T, security config.Security, store session.Store) *gin.Engine {
+	t.Helper()
+
+	router := gin.New()
+	router.GET("/login", func(c *gin.Context) {
+		_, err := session.Start(c, security, store, c.Query("user"))
+
+		assert.NoError(t, err)
+	})
+	router.GET("/logout", func(c *gin.Context) {
+		assert.NoError(t, session.End(c, security, store))
+	})
+
+	authorised := router.Group("/", session.Authenticate(security, store, ""))
+	authorised.GET("/add", func(c *gin.Context) {
+		basket := session.Get[Basket](c, "basket")
+		basket.Items = append(basket.Items, c.Query("item"))
+
+		assert.NoError(t, session.Set(c, "basket", basket))
+	})
+	authorised.GET("/basket", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"user":   tonic.Get[string](c, tonic.SubjectClaim),
+			"basket": session.Get[Basket](c, "basket").Items,
+		})
+	})
+
+	return router
+}
+
+func Request(t *testing.T, router *gin.Engine, path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func ExampleStart() {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+	store := session.NewMemoryStore()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/login", nil)
+
+	s, err := session.Start(c, security, store, "user")
+
+	fmt.Println(err, s.Subject)
+	fmt.Println(session.Set(c, "theme", "dark"), session.Get[string](c, "theme"))
+	fmt.Println(w.Result().Cookies()[0].Name)
+
+	sessions, _ := store.List()
+
+	fmt.Println(len(sessions), string(sessions[0].Values["theme"]))
+
+	// Output:
+	// <nil> user
+	// <nil> dark
+	// GinAndTonicSession
+	// 1 "dark"
+}
+
+func TestAuthenticate(t *testing.T) {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+	t.Run("Session values are kept in the store", func(t *testing.T) {
+		t.Parallel()
+
+		router := Router(t, security, session.NewMemoryStore())
+		login := Request(t, router, "/login?user=user").Result().Cookies()
+
+		assert.Len(t, login, 1)
+		assert.True(t, login[0].HttpOnly)
+
+		assert.Equal(t, http.StatusOK, Request(t, router, "/add?item=gin", login...).Code)
+		assert.Equal(t, http.StatusOK, Request(t, router, "/add?item=tonic", login...).Code)
+
+		w := Request(t, router, "/basket", login...)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"user": "user", "basket": ["gin", "tonic"]}`, w.Body.String())
+	})
+
+	t.Run("Requests without a valid session are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		store := session.NewMemoryStore()
+		router := Router(t, security, store)
+		login := Request(t, router, "/login").Result().Cookies()
+
+		assert.Equal(t, http.StatusUnauthorized, Request(t, router, "/basket").Code)
+		assert.Equal(t, http.StatusUnauthorized, Request(t, router, "/basket",
+			&http.Cookie{Name: session.Name, Value: "id"}).Code)
+		assert.Equal(t, http.StatusUnauthorized, Request(t, router, "/basket",
+			&http.Cookie{Name: session.Name, Value: "id." + login[0].Value}).Code)
+
+		sessions, err := store.List()
+
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+
+		// Killing the session in the store logs the user out.
+		assert.Equal(t, http.StatusOK, Request(t, router, "/basket", login...).Code)
+		assert.NoError(t, store.Delete(sessions[0].ID))
+		assert.Equal(t, http.StatusUnauthorized, Request(t, router, "/basket", login...).Code)
+	})
+
+	t.Run("Sessions signed with another secret are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		store := session.NewMemoryStore()
+		other := security
+		other.Secret = "other"
+
+		login := Request(t, Router(t, other, store), "/login").Result().Cookies()
+
+		assert.Equal(t, http.StatusUnauthorized, Request(t, Router(t, security, store), "/basket", login...).Code)
+	})
+
+	t.Run("Missing sessions redirect without reporting an error", func(t *testing.T) {
+		t.Parallel()
+
+		var errs []*gin.Error
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Next()
+			errs = c.Errors
+		})
+		router.Use(session.Authenticate(security, session.NewMemoryStore(), "/login"))
+		register.Ping(router)
+
+		w := Request(t, router, "/ping")
+
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.Equal(t, "/login?next=%2Fping", w.Header().Get("Location"))
+		assert.Empty(t, errs)
+
+		w = Request(t, router, "/ping", &http.Cookie{Name: session.Name, Value: "garbage"})
+
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.Len(t, errs, 1)
+		assert.ErrorIs(t, errs[0], session.ErrInvalidSession)
+	})
+
+	t.Run("Invalid settings will fail", func(t *testing.T) {
+		t.Parallel()
+
+		s := security
+		s.Cookie.SameSite = config.SameSiteNone
+
+		router := gin.New()
+		router.Use(session.Authenticate(s, session.NewMemoryStore(), ""))
+		register.Ping(router)
+
+		assert.Equal(t, http.StatusInternalServerError, Request(t, router, "/ping").Code)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/login", nil)
+
+		_, err := session.Start(c, s, session.NewMemoryStore(), "")
+
+		assert.ErrorIs(t, err, config.ErrInvalidCookie)
+
+		_, err = session.Start(c, config.Security{Secret: "secret"}, session.NewMemoryStore(), "")
+
+		assert.ErrorIs(t, err, tonic.ErrInvalidTTL)
+		assert.Error(t, session.End(c, s, session.NewMemoryStore()))
+	})
+
+	t.Run("Signing keys need an explicit secret", func(t *testing.T) {
+		t.Parallel()
+
+		s := config.Security{PrivateKey: "../testdata/ecdsa.key", SessionTTL: time.Hour}
+
+		router := gin.New()
+		router.Use(session.Authenticate(s, session.NewMemoryStore(), ""))
+		register.Ping(router)
+
+		assert.Equal(t, http.StatusInternalServerError, Request(t, router, "/ping").Code)
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/login", nil)
+
+		_, err := session.Start(c, s, session.NewMemoryStore(), "")
+
+		assert.ErrorIs(t, err, tonic.ErrMissingSecret)
+
+		s.Secret = "secret"
+		router = Router(t, s, session.NewMemoryStore())
+		w := Request(t, router, "/login")
+
+		assert.Equal(t, http.StatusOK, Request(t, router, "/basket", w.Result().Cookies()...).Code)
+	})
+
+	t.Run("Sessions can be used with CSRF protection", func(t *testing.T) {
+		t.Parallel()
+
+		store := session.NewMemoryStore()
+		router := Router(t, security, store)
+		router.POST("/submit", session.Authenticate(security, store, ""),
+			cookie.Protect(security), func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+		router.GET("/form", session.Authenticate(security, store, ""), func(c *gin.Context) {
+			c.String(http.StatusOK, cookie.CSRFToken(c, security))
+		})
+
+		login := Request(t, router, "/login").Result().Cookies()
+		w := Request(t, router, "/form", login...)
+		cookies := append(login, w.Result().Cookies()...)
+
+		req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+		req.Header.Set(cookie.CSRFHeader, w.Body.String())
+
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Sessions can be authorised", func(t *testing.T) {
+		t.Parallel()
+
+		store := session.NewMemoryStore()
+		router := Router(t, security, store)
+		router.GET("/account", session.Authenticate(security, store, ""), tonic.Require(),
+			func(c *gin.Context) {
+				claims, _ := tonic.Principal[map[string]any](c)
+
+				c.String(http.StatusOK, "%v", claims[tonic.SubjectClaim])
+			})
+		router.GET("/admin", session.Authenticate(security, store, ""), tonic.Require("admin"),
+			func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+		login := Request(t, router, "/login?user=user").Result().Cookies()
+		w := Request(t, router, "/account", login...)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "user", w.Body.String())
+		assert.Equal(t, http.StatusForbidden, Request(t, router, "/admin", login...).Code)
+		assert.Equal(t, http.StatusUnauthorized, Request(t, router, "/account").Code)
+	})
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+	t.Run("Sessions can be used in an authentication chain", func(t *testing.T) {
+		t.Parallel()
+
+		store := session.NewMemoryStore()
+		router := Router(t, security, store)
+		router.GET("/whoami", auth.Chain("", session.NewAuthenticator(security, store)),
+			func(c *gin.Context) {
+				c.String(http.StatusOK, "%s %s", c.GetString(auth.MethodKey), c.GetString(tonic.SubjectClaim))
+			})
+
+		login := Request(t, router, "/login?user=user").Result().Cookies()
+		w := Request(t, router, "/whoami", login...)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "session user", w.Body.String())
+		assert.Equal(t, http.StatusUnauthorized, Request(t, router, "/whoami").Code)
+	})
+
+	t.Run("Invalid settings are misconfigured", func(t *testing.T) {
+		t.Parallel()
+
+		s := security
+		s.Cookie.SameSite = config.SameSiteNone
+
+		a := session.NewAuthenticator(s, session.NewMemoryStore())
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+		assert.Equal(t, session.Method, a.Method())
+		assert.ErrorIs(t, a.Authenticate(c), tonic.ErrMisconfigured)
+	})
+}
+
+func TestStart(t *testing.T) {
+	t.Run("Starting a session ends the previous one", func(t *testing.T) {
+		t.Parallel()
+
+		security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+		store := session.NewMemoryStore()
+		router := Router(t, security, store)
+		first := Request(t, router, "/login").Result().Cookies()
+		second := Request(t, router, "/login", first...).Result().Cookies()
+
+		assert.NotEqual(t, first[0].Value, second[0].Value)
+		assert.Equal(t, http.StatusUnauthorized, Request(t, router, "/basket", first...).Code)
+		assert.Equal(t, http.StatusOK, Request(t, router, "/basket", second...).Code)
+
+		sessions, err := store.List()
+
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+	})
+}
+
+func TestEnd(t *testing.T) {
+	t.Run("Ending a session deletes it and clears the cookie", func(t *testing.T) {
+		t.Parallel()
+
+		security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+		store := session.NewMemoryStore()
+		router := Router(t, security, store)
+		login := Request(t, router, "/login").Result().Cookies()
+		cleared := Request(t, router, "/logout", login...).Result().Cookies()
+
+		assert.Len(t, cleared, 1)
+		assert.Less(t, cleared[0].MaxAge, 0)
+		assert.Equal(t, http.StatusUnauthorized, Request(t, router, "/basket", login...).Code)
+
+		sessions, err := store.List()
+
+		assert.NoError(t, err)
+		assert.Empty(t, sessions)
+
+		// Ending a session without a cookie still clears the cookie.
+		assert.Len(t, Request(t, router, "/logout").Result().Cookies(), 1)
+	})
+}
+
+func TestGet(t *testing.T) {
+	t.Run("Values that cannot be decoded will panic", func(t *testing.T) {
+		t.Parallel()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+		assert.Equal(t, "", session.Get[string](c, "missing"))
+		assert.ErrorIs(t, session.Set(c, "key", "value"), session.ErrNoSession)
+		assert.ErrorIs(t, session.Delete(c, "key"), session.ErrNoSession)
+
+		_, err := session.Start(c, config.Security{Secret: "secret", SessionTTL: time.Hour},
+			session.NewMemoryStore(), "")
+
+		assert.NoError(t, err)
+		assert.NoError(t, session.Set(c, "key", "value"))
+		assert.Panics(t, func() { session.Get[int](c, "key") })
+		assert.Error(t, session.Set(c, "key", make(chan int)))
+		assert.NoError(t, session.Delete(c, "key"))
+		assert.Equal(t, 0, session.Get[int](c, "key"))
+	})
+}
@@ -0,0 +1,192 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/domdavis/tonic"
+)
+
+// A Store holds sessions. Expired sessions must not be returned by the store,
+// and should be removed by it.
+type Store interface {
+	// Load the session with the given ID, returning ErrNoSession if there is
+	// no such session, or it has expired.
+	Load(id string) (*Session, error)
+
+	// Save the session, replacing any existing session with the same ID.
+	Save(session *Session) error
+
+	// Delete the session with the given ID. Deleting a session that doesn't
+	// exist is not an error.
+	Delete(id string) error
+
+	// List all the sessions held in the store that have not expired.
+	List() ([]*Session, error)
+}
+
+// MemoryStore is a Store held in memory. Expired sessions are removed whenever
+// the store is used. A MemoryStore is safe for concurrent use, but cannot be
+// shared between instances of a service.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// FileStore is a Store that is persisted to a JSON file so sessions survive
+// restarts. The file is rewritten on every change. A FileStore is safe for
+// concurrent use, but the file should not be shared between running services.
+type FileStore struct {
+	memory *MemoryStore
+	path   string
+}
+
+const storeMode = 0o600
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: map[string]*Session{}}
+}
+
+// Load the session with the given ID.
+func (m *MemoryStore) Load(id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep()
+
+	s, ok := m.sessions[id]
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoSession, id)
+	}
+
+	return s.clone(), nil
+}
+
+// Save the session.
+func (m *MemoryStore) Save(session *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep()
+	m.sessions[session.ID] = session.clone()
+
+	return nil
+}
+
+// Delete the session with the given ID.
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, id)
+
+	return nil
+}
+
+// List the sessions in the store, ordered by expiry.
+func (m *MemoryStore) List() ([]*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep()
+
+	sessions := make([]*Session, 0, len(m.sessions))
+
+	for _, s := range m.sessions {
+		sessions = append(sessions, s.clone())
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Expires.Before(sessions[j].Expires)
+	})
+
+	return sessions, nil
+}
+
+// sweep removes expired sessions. The lock must be held by the caller.
+func (m *MemoryStore) sweep() {
+	now := time.Now()
+
+	for id, s := range m.sessions {
+		if now.After(s.Expires) {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+// NewFileStore returns a FileStore persisted to the given path. Any existing
+// sessions in the file are loaded. The file will be created on the first
+// change if it doesn't exist.
+func NewFileStore(path string) (*FileStore, error) {
+	f := &FileStore{memory: NewMemoryStore(), path: path}
+	b, err := os.ReadFile(path)
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return f, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to read sessions: %w", err)
+	}
+
+	if err = json.Unmarshal(b, &f.memory.sessions); err != nil {
+		return nil, fmt.Errorf("failed to parse sessions %s: %w", path, err)
+	}
+
+	f.memory.sweep()
+
+	return f, nil
+}
+
+// Load the session with the given ID.
+func (f *FileStore) Load(id string) (*Session, error) {
+	return f.memory.Load(id)
+}
+
+// Save the session.
+func (f *FileStore) Save(session *Session) error {
+	f.memory.mu.Lock()
+	defer f.memory.mu.Unlock()
+
+	f.memory.sweep()
+	f.memory.sessions[session.ID] = session.clone()
+
+	return f.save()
+}
+
+// Delete the session with the given ID.
+func (f *FileStore) Delete(id string) error {
+	f.memory.mu.Lock()
+	defer f.memory.mu.Unlock()
+
+	if _, ok := f.memory.sessions[id]; !ok {
+		return nil
+	}
+
+	delete(f.memory.sessions, id)
+
+	return f.save()
+}
+
+// List the sessions in the store, ordered by expiry.
+func (f *FileStore) List() ([]*Session, error) {
+	return f.memory.List()
+}
+
+// save the sessions to the file, replacing it atomically. The memory lock must
+// be held by the caller.
+func (f *FileStore) save() error {
+	b, _ := json.Marshal(f.memory.sessions)
+
+	if err := tonic.WriteFileAtomic(f.path, b, storeMode); err != nil {
+		return fmt.Errorf("failed to save sessions: %w", err)
+	}
+
+	return nil
+}
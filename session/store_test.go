@@ -0,0 +1,141 @@
+package session_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleMemoryStore() {
+	store := session.NewMemoryStore()
+
+	_ = store.Save(&session.Session{ID: "a", Subject: "user", Expires: time.Now().Add(time.Hour)})
+	_ = store.Save(&session.Session{ID: "b", Subject: "user", Expires: time.Now().Add(time.Minute)})
+
+	// Kill all the sessions for a user.
+	sessions, _ := store.List()
+
+	for _, s := range sessions {
+		if s.Subject == "user" {
+			fmt.Println(s.ID, store.Delete(s.ID))
+		}
+	}
+
+	sessions, _ = store.List()
+
+	fmt.Println(len(sessions))
+
+	// Output:
+	// b <nil>
+	// a <nil>
+	// 0
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Run("Expired sessions are removed", func(t *testing.T) {
+		t.Parallel()
+
+		store := session.NewMemoryStore()
+
+		assert.NoError(t, store.Save(&session.Session{ID: "expired", Expires: time.Now().Add(-time.Second)}))
+		assert.NoError(t, store.Save(&session.Session{ID: "live", Expires: time.Now().Add(time.Hour)}))
+
+		_, err := store.Load("expired")
+
+		assert.ErrorIs(t, err, session.ErrNoSession)
+
+		sessions, err := store.List()
+
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, "live", sessions[0].ID)
+	})
+
+	t.Run("Sessions are copied in and out of the store", func(t *testing.T) {
+		t.Parallel()
+
+		store := session.NewMemoryStore()
+		s := &session.Session{
+			ID:      "id",
+			Expires: time.Now().Add(time.Hour),
+			Values:  map[string]json.RawMessage{"a": json.RawMessage(`1`)},
+		}
+
+		assert.NoError(t, store.Save(s))
+
+		s.Values["a"] = json.RawMessage(`2`)
+		loaded, err := store.Load("id")
+
+		assert.NoError(t, err)
+		assert.Equal(t, json.RawMessage(`1`), loaded.Values["a"])
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	t.Run("Sessions survive a restart", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "sessions.json")
+		store, err := session.NewFileStore(path)
+
+		assert.NoError(t, err)
+		assert.NoError(t, store.Save(&session.Session{ID: "a", Subject: "user", Expires: time.Now().Add(time.Hour)}))
+		assert.NoError(t, store.Save(&session.Session{ID: "b", Expires: time.Now().Add(time.Hour)}))
+		assert.NoError(t, store.Delete("b"))
+		assert.NoError(t, store.Delete("missing"))
+
+		info, err := os.Stat(path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		store, err = session.NewFileStore(path)
+
+		assert.NoError(t, err)
+
+		s, err := store.Load("a")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "user", s.Subject)
+
+		_, err = store.Load("b")
+
+		assert.ErrorIs(t, err, session.ErrNoSession)
+
+		sessions, err := store.List()
+
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+	})
+
+	t.Run("Invalid files cannot be loaded", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "sessions.json")
+
+		assert.NoError(t, os.WriteFile(path, []byte("garbage"), 0o600))
+
+		_, err := session.NewFileStore(path)
+
+		assert.Error(t, err)
+
+		_, err = session.NewFileStore(t.TempDir())
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Unwritable files fail to save", func(t *testing.T) {
+		t.Parallel()
+
+		store, err := session.NewFileStore(filepath.Join(t.TempDir(), "missing", "sessions.json"))
+
+		assert.NoError(t, err)
+		assert.Error(t, store.Save(&session.Session{ID: "a", Expires: time.Now().Add(time.Hour)}))
+	})
+}
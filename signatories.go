@@ -0,0 +1,62 @@
+package tonic
+
+import (
+	"sync"
+
+	"github.com/domdavis/tonic/config"
+)
+
+// Signatories holds the Signatories built for each set of security settings so
+// every handler and middleware using the same settings shares a single
+// Signatory, and with it the KeyRing and SecretProvider. Without this, a
+// Signatory built for a single request would load the secret afresh while
+// long-lived Signatories would only see it once their KeyRing was rotated.
+// Signatories is safe for concurrent use.
+type Signatories struct {
+	build func(security config.Security) (*Signatory, error)
+
+	mu    sync.Mutex
+	built map[config.Security]*Signatory
+}
+
+//nolint:gochecknoglobals // Needs to be global so the Signatories are shared.
+var shared = NewSignatories(NewSignatory)
+
+// NewSignatories returns Signatories that use the given function to build the
+// Signatory for a set of security settings the first time they are seen.
+func NewSignatories(build func(security config.Security) (*Signatory, error)) *Signatories {
+	return &Signatories{build: build, built: map[config.Security]*Signatory{}}
+}
+
+// SharedSignatory returns a copy of the Signatory built by NewSignatory for the
+// given security settings, sharing its KeyRing and SecretProvider with every
+// other Signatory returned for the same settings.
+func SharedSignatory(security config.Security) (*Signatory, error) {
+	return shared.Get(security)
+}
+
+// Get returns a copy of the Signatory for the given security settings, building
+// it if needed. The copy can be changed, for example to set the Revocations
+// for a request, without affecting the shared Signatory, but shares its KeyRing
+// and SecretProvider. Failures are not kept, so the Signatory will be built
+// again on the next call.
+func (s *Signatories) Get(security config.Security) (*Signatory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	signatory, ok := s.built[security]
+
+	if !ok {
+		var err error
+
+		if signatory, err = s.build(security); err != nil {
+			return nil, err
+		}
+
+		s.built[security] = signatory
+	}
+
+	c := *signatory
+
+	return &c, nil
+}
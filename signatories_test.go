@@ -0,0 +1,87 @@
+package tonic_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func ExampleSharedSignatory() {
+	security := config.Security{Secret: "secret", SessionTTL: time.Hour}
+
+	signer, _ := tonic.SharedSignatory(security)
+	verifier, _ := tonic.SharedSignatory(security)
+
+	// Each call returns a copy that can be changed without affecting the
+	// others, but the keys are shared.
+	signer.Type = tonic.AccessType
+	token, _ := signer.Sign(&gin.Context{})
+
+	fmt.Println(signer.Keys == verifier.Keys, verifier.Type == "")
+	fmt.Println(verifier.Validate(&gin.Context{}, token))
+
+	// Output:
+	// true true
+	// <nil>
+}
+
+func TestSignatories_Get(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Signatories are only built once", func(t *testing.T) {
+		t.Parallel()
+
+		built := 0
+		signatories := tonic.NewSignatories(func(security config.Security) (*tonic.Signatory, error) {
+			built++
+
+			return tonic.NewSignatory(security)
+		})
+
+		first, err := signatories.Get(config.Security{Secret: "first"})
+
+		assert.NoError(t, err)
+
+		second, err := signatories.Get(config.Security{Secret: "first"})
+
+		assert.NoError(t, err)
+		assert.NotSame(t, first, second)
+		assert.Same(t, first.Keys, second.Keys)
+
+		other, err := signatories.Get(config.Security{Secret: "second"})
+
+		assert.NoError(t, err)
+		assert.NotSame(t, first.Keys, other.Keys)
+		assert.Equal(t, 2, built)
+	})
+
+	t.Run("Failures are not kept", func(t *testing.T) {
+		t.Parallel()
+
+		failed := errors.New("failed")
+		built := 0
+		signatories := tonic.NewSignatories(func(security config.Security) (*tonic.Signatory, error) {
+			built++
+
+			if built == 1 {
+				return nil, failed
+			}
+
+			return tonic.NewSignatory(security)
+		})
+
+		_, err := signatories.Get(config.Security{})
+
+		assert.ErrorIs(t, err, failed)
+
+		_, err = signatories.Get(config.Security{})
+
+		assert.NoError(t, err)
+	})
+}
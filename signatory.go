@@ -1,14 +1,18 @@
 package tonic
 
 import (
+	"crypto"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/domdavis/tonic/config"
+	"github.com/domdavis/tonic/jwk"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -18,51 +22,337 @@ type Signatory struct {
 	Secret string
 	TTL    time.Duration
 	Method jwt.SigningMethod
+
+	// PrivateKey is used to sign tokens when using an asymmetric Method. If no
+	// Method is set then one will be chosen based on the key type.
+	PrivateKey crypto.Signer
+
+	// PublicKey is used to validate tokens when using an asymmetric Method. If
+	// no PublicKey is set then the public half of PrivateKey is used.
+	PublicKey crypto.PublicKey
+
+	// Keys, if set, are used in place of the Secret, PrivateKey, and
+	// PublicKey. Tokens are signed with the active key and carry its ID in the
+	// kid header, which Validate uses to pick the key to verify with.
+	Keys *KeyRing
+
+	// Source, if set, is used in place of Keys to look up the key used to
+	// validate tokens. This allows tokens signed by another service to be
+	// validated using keys it publishes. Tokens must carry a kid header to be
+	// validated against a Source.
+	Source KeySource
+
+	// Methods is the list of algorithms accepted when validating tokens.
+	// Regardless of Methods, a token is only accepted if its algorithm matches
+	// the method of the key used to verify it.
+	Methods []string
+
+	// Issuer is set as the iss claim on signed tokens. If set, validated tokens
+	// must have a matching iss claim.
+	Issuer string
+
+	// Audience is set as the aud claim on signed tokens. If set, validated
+	// tokens must be intended for at least one of the audiences.
+	Audience []string
+
+	// Leeway allowed for clock skew when checking the exp, nbf, and iat claims.
+	Leeway time.Duration
+
+	// Type is set as the typ header on signed tokens so that tokens issued for
+	// different purposes, such as sessions and API access, can be told apart.
+	// If set, validated tokens must have a typ header matching Type, or one of
+	// the AcceptTypes.
+	Type string
+
+	// AcceptTypes lists the token types accepted by Validate in addition to
+	// Type.
+	AcceptTypes []string
+
+	// Secrets, if set, provides the secret used in place of Secret. The
+	// provider is checked each time a token is signed or validated, and the
+	// KeyRing rotated if the secret has changed, so tokens signed with the
+	// previous secret remain valid for the KeyRing's grace period. The KeyRing
+	// is created by NewSignatory, or by Initialise if Keys is nil, and must be
+	// shared by every copy of the Signatory.
+	Secrets SecretProvider
+
+	// Encrypt, if true, encrypts signed tokens as a compact JWE using direct
+	// A256GCM encryption, with the key derived from the secret, so the claims
+	// cannot be read by the client. Signatories using a PrivateKey must also
+	// have an explicit Secret, see CheckEncryption. Encrypted tokens are always
+	// decrypted by Validate, regardless of Encrypt.
+	Encrypt bool
+
+	// Revocations, if set, is checked by Validate to reject tokens that have
+	// been revoked. Tokens with a sub claim are signed with the subject's
+	// current generation so they can all be revoked at once.
+	Revocations RevocationStore
 }
 
-// ErrInvalidTTL is returned if a TTL is too short, or negative.
-var ErrInvalidTTL = errors.New("invalid TTL")
+// Signatory errors.
+var (
+	ErrInvalidTTL       = errors.New("invalid TTL")
+	ErrUnknownKey       = errors.New("unknown key")
+	ErrWrongAlgorithm   = errors.New("wrong algorithm")
+	ErrWrongType        = errors.New("wrong token type")
+	ErrMissingToken     = errors.New("missing token")
+	ErrMalformed        = errors.New("malformed token")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// ErrMisconfigured is returned by authenticators with invalid settings, such as
+// keys that cannot be loaded. It is a fault with the service rather than the
+// request, so should result in http.StatusInternalServerError rather than a
+// failure to authenticate.
+var ErrMisconfigured = errors.New("invalid configuration")
 
 //nolint:gochecknoglobals // Needs to be global as it's a fallback.
-var defaultSecret string
+var (
+	defaultSecret string
+	defaultOnce   sync.Once
+)
+
+// Token types set in the typ header.
+const (
+	// SessionType is used for session tokens, such as those held in cookies.
+	SessionType = "session+jwt"
+
+	// AccessType is used for bearer access tokens, as defined in RFC 9068.
+	AccessType = "at+jwt"
+
+	// RefreshType is used for refresh tokens.
+	RefreshType = "refresh+jwt"
+)
+
+const (
+	keyIDHeader = "kid"
+	typeHeader  = "typ"
+)
+
+// NewSignatory returns an initialised Signatory using the given security
+// settings. If a private or public key path is set then the keys will be loaded
+// and used in place of the secret. Any retired secrets or keys are added to the
+// Signatory's KeyRing so tokens signed with them remain valid until the
+// SessionTTL has passed since the Signatory was created. If EncryptSessions is
+// set then the Signatory must be able to encrypt tokens, see CheckEncryption.
+// If both keys are set then the public key must match the private key.
+func NewSignatory(security config.Security) (*Signatory, error) {
+	s := &Signatory{
+		Secret:   security.Secret,
+		TTL:      security.SessionTTL,
+		Methods:  config.List(security.Algorithms),
+		Issuer:   security.Issuer,
+		Audience: config.List(security.Audience),
+		Leeway:   security.Leeway,
+	}
+
+	if security.PrivateKey != "" {
+		key, err := LoadPrivateKey(security.PrivateKey)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing key: %w", err)
+		}
+
+		s.PrivateKey = key
+	}
+
+	if security.PublicKey != "" {
+		key, err := LoadPublicKey(security.PublicKey)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to load verification key: %w", err)
+		}
+
+		s.PublicKey = key
+	}
+
+	if s.PrivateKey != nil && s.PublicKey != nil {
+		key, ok := s.PrivateKey.Public().(interface{ Equal(x crypto.PublicKey) bool })
+
+		if !ok || !key.Equal(s.PublicKey) {
+			return nil, fmt.Errorf("invalid security key: %w", ErrKeyMismatch)
+		}
+	}
+
+	if key := s.publicKey(); key != nil {
+		method, err := MethodFor(key)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid security key: %w", err)
+		}
+
+		s.Method = method
+	}
+
+	if security.SecretFile != "" {
+		if err := s.initialiseSecret(security.SecretFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if security.StrictSecret && gin.Mode() == gin.ReleaseMode && s.publicKey() == nil &&
+		(s.Secret == "" || s.Secret == config.RandomSecret) {
+		return nil, ErrRandomSecret
+	}
+
+	s.Initialise()
+
+	if err := s.initialiseKeys(security); err != nil {
+		return nil, err
+	}
+
+	if security.EncryptSessions {
+		if err := s.CheckEncryption(); err != nil {
+			return nil, fmt.Errorf("cannot encrypt sessions: %w", err)
+		}
+	}
 
-const expiryClaim = "exp"
+	return s, nil
+}
 
 // Sign a set of claims from the gin context.
 func (s *Signatory) Sign(ctx *gin.Context, claims ...string) (string, error) {
+	payload := jwt.MapClaims{}
+
+	for _, claim := range claims {
+		payload[claim], _ = ctx.Get(claim)
+	}
+
+	return s.Issue(payload)
+}
+
+// Issue a token for the given claims. The registered exp, nbf, iat, iss, and
+// aud claims will be set by the Signatory, overwriting any existing values. A
+// unique jti claim is set if the claims don't already have one.
+func (s *Signatory) Issue(claims jwt.MapClaims) (string, error) {
 	if s.TTL <= 0 {
 		return "", fmt.Errorf("%w: %v", ErrInvalidTTL, s.TTL)
 	}
 
 	s.Initialise()
 
+	if err := s.refreshSecret(); err != nil {
+		return "", err
+	}
+
 	payload := jwt.MapClaims{}
 
-	for _, claim := range claims {
-		payload[claim], _ = ctx.Get(claim)
+	for k, v := range claims {
+		payload[k] = v
 	}
 
-	payload[expiryClaim] = time.Now().Add(s.TTL).Unix()
+	s.register(payload, time.Now())
+
+	if err := s.generation(payload); err != nil {
+		return "", err
+	}
 
-	token := jwt.NewWithClaims(s.Method, payload)
-	tokenString, err := token.SignedString([]byte(s.Secret))
+	key, err := s.signingKey()
 
 	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, payload)
+
+	if key.ID != "" {
+		token.Header[keyIDHeader] = key.ID
+	}
+
+	if s.Type != "" {
+		token.Header[typeHeader] = s.Type
+	}
+
+	tokenString, err := token.SignedString(key.Signing)
+
+	switch {
+	case err != nil:
 		err = fmt.Errorf("failed to sign JWT: %w", err)
+	case s.Encrypt:
+		var encryption *Key
+
+		if encryption, err = s.encryptionKey(); err == nil {
+			tokenString, err = encrypt(encryption, tokenString)
+		}
 	}
 
 	return tokenString, err
 }
 
+// Renew a valid token, returning a new token with the same private claims and
+// fresh registered claims.
+func (s *Signatory) Renew(tokenString string) (string, error) {
+	claims, err := s.Parse(tokenString)
+
+	if err != nil {
+		return "", err
+	}
+
+	return s.Issue(Private(claims))
+}
+
 // Validate the given token, adding the claims to the context if it is valid.
-// Returns true if the token is valid, false otherwise.
-func (s *Signatory) Validate(ctx *gin.Context, tokenString string) bool {
-	token, err := jwt.Parse(tokenString, func(_ *jwt.Token) (any, error) {
-		return []byte(s.Secret), nil
-	})
+// Each claim is set under its own name, and the jwt.MapClaims holding all of
+// the claims is set under PrincipalKey so it can be read using Principal.
+// A valid token must use an accepted algorithm, have a valid signature, not
+// have expired, have valid nbf, iat, iss, and aud claims, and not have been
+// revoked. An error is returned if the token is invalid, which can be checked
+// using errors.Is to find out why. The reasons are ErrMissingToken,
+// ErrMalformed, ErrInvalidSignature, ErrWrongAlgorithm, ErrUnknownKey,
+// ErrWrongType, ErrExpired, ErrNotYetValid, ErrInvalidClaim, ErrInvalidIssuer,
+// ErrInvalidAudience, and ErrRevoked.
+func (s *Signatory) Validate(ctx *gin.Context, tokenString string) error {
+	claims, err := s.Parse(tokenString)
 
 	if err != nil {
-		return false
+		return err
+	}
+
+	for k, v := range claims {
+		ctx.Set(k, v)
+	}
+
+	ctx.Set(PrincipalKey, claims)
+
+	return nil
+}
+
+// Parse and validate a token, returning its claims. The errors returned are the
+// same as for Validate.
+func (s *Signatory) Parse(tokenString string) (jwt.MapClaims, error) {
+	return s.parse(tokenString)
+}
+
+// parse and validate a token using the given additional parser options.
+func (s *Signatory) parse(tokenString string, extra ...jwt.ParserOption) (jwt.MapClaims, error) {
+	if tokenString == "" {
+		return nil, ErrMissingToken
+	}
+
+	if err := s.refreshSecret(); err != nil {
+		return nil, err
+	}
+
+	if encrypted(tokenString) {
+		var err error
+
+		if tokenString, err = decrypt(tokenString, s.decryptionKey); err != nil {
+			return nil, err
+		}
+	}
+
+	options := append([]jwt.ParserOption{jwt.WithoutClaimsValidation()}, extra...)
+	token, err := jwt.Parse(tokenString, s.verificationKey, options...)
+
+	switch {
+	case err == nil:
+	case errors.Is(err, jwt.ErrTokenUnverifiable):
+		// The error came from verificationKey and is already typed.
+		return nil, fmt.Errorf("invalid token: %w", err)
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSignature, err.Error())
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrMalformed, err.Error())
 	}
 
 	// Not entirely sure how it's possible to get here without MapClaims, and
@@ -75,29 +365,241 @@ func (s *Signatory) Validate(ctx *gin.Context, tokenString string) bool {
 	//nolint:errcheck // see above.
 	claims, _ := token.Claims.(jwt.MapClaims)
 
-	for k, v := range claims {
-		ctx.Set(k, v)
+	if err = s.verifyType(token); err != nil {
+		return nil, err
+	}
+
+	if err = s.verify(claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err = s.verifyRevocation(claims); err != nil {
+		return nil, err
 	}
 
-	return true
+	return claims, nil
+}
+
+// verifyType ensures the token's typ header is acceptable.
+func (s *Signatory) verifyType(token *jwt.Token) error {
+	if s.Type == "" {
+		return nil
+	}
+
+	t, _ := token.Header[typeHeader].(string)
+
+	if strings.EqualFold(t, s.Type) {
+		return nil
+	}
+
+	for _, accepted := range s.AcceptTypes {
+		if strings.EqualFold(t, accepted) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q", ErrWrongType, t)
 }
 
 // Initialise a Signatory, ensuring a secret is set. If no secret is set then
-// the default secret is used. This is a random string generated on first use.
+// the default secret is used. This is a random string generated on first use,
+// so is only suitable for a single instance of a service.
+// If no Method is set then it will be chosen from the PrivateKey or PublicKey,
+// falling back to HS512 if there are no keys. If Secrets is set without Keys
+// then a KeyRing is created holding the current secret. Initialise should be
+// called before a Signatory built by hand is copied or used concurrently.
 func (s *Signatory) Initialise() {
-	if defaultSecret == "" {
-		defaultSecret = GenerateSecret(rand.Reader)
-	}
+	defaultOnce.Do(func() { defaultSecret = GenerateSecret(rand.Reader) })
 
 	if s.Secret == "" || s.Secret == config.RandomSecret {
 		s.Secret = defaultSecret
 	}
 
+	if s.Secrets != nil && s.Keys == nil && s.publicKey() == nil {
+		if secret, err := s.Secrets.Secret(); err == nil {
+			s.Secret = secret
+		}
+
+		s.Keys = NewKeyRing(NewSecretKey(s.Secret), s.TTL)
+	}
+
+	if s.Method == nil {
+		s.Method, _ = MethodFor(s.publicKey())
+	}
+
 	if s.Method == nil {
 		s.Method = jwt.SigningMethodHS512
 	}
 }
 
+// JWKS returns the JSON Web Key Set containing the public keys used by the
+// Signatory. Secrets are never published, so a Signatory using HMAC will return
+// an empty set.
+func (s *Signatory) JWKS() jwk.Set {
+	if s.Keys != nil {
+		return s.Keys.JWKS()
+	}
+
+	set := jwk.Set{Keys: []jwk.Key{}}
+
+	if key, ok := s.activeKey().JWK(); ok {
+		set.Keys = append(set.Keys, key)
+	}
+
+	return set
+}
+
+// Lifetime returns the length of time tokens issued by the Signatory are valid
+// for.
+func (s *Signatory) Lifetime() time.Duration {
+	return s.TTL
+}
+
+// initialiseSecret watches the given file for the secret.
+func (s *Signatory) initialiseSecret(path string) error {
+	secrets, err := NewWatchedSecret(path)
+
+	if err != nil {
+		return fmt.Errorf("failed to load secret: %w", err)
+	}
+
+	s.Secrets = secrets
+	s.Secret, _ = secrets.Secret()
+
+	return nil
+}
+
+// initialiseKeys builds the KeyRing from the security settings.
+func (s *Signatory) initialiseKeys(security config.Security) error {
+	active := NewSecretKey(s.Secret)
+
+	if s.PrivateKey != nil || s.PublicKey != nil {
+		var err error
+
+		if s.PrivateKey != nil {
+			active, err = NewPrivateKey(s.PrivateKey)
+		} else {
+			active, err = NewPublicKey(s.PublicKey)
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid security key: %w", err)
+		}
+	}
+
+	s.Keys = NewKeyRing(active, security.SessionTTL)
+
+	for _, secret := range config.List(security.RetiredSecrets) {
+		s.Keys.Retire(NewSecretKey(secret))
+	}
+
+	for _, path := range config.List(security.RetiredKeys) {
+		public, err := LoadPublicKey(path)
+
+		if err != nil {
+			return fmt.Errorf("failed to load retired key: %w", err)
+		}
+
+		key, err := NewPublicKey(public)
+
+		if err != nil {
+			return fmt.Errorf("invalid retired key %s: %w", path, err)
+		}
+
+		s.Keys.Retire(key)
+	}
+
+	return nil
+}
+
+// activeKey returns the key used to sign tokens. If the Signatory has no
+// KeyRing then the key is built from the Secret or PrivateKey.
+func (s *Signatory) activeKey() *Key {
+	if s.Keys != nil {
+		return s.Keys.Active()
+	}
+
+	if _, ok := s.Method.(*jwt.SigningMethodHMAC); ok || s.Method == nil {
+		return &Key{Method: s.Method, Signing: []byte(s.Secret), Verifying: []byte(s.Secret)}
+	}
+
+	return &Key{Method: s.Method, Signing: s.PrivateKey, Verifying: s.publicKey()}
+}
+
+// signingKey returns the key used to sign tokens.
+func (s *Signatory) signingKey() (*Key, error) {
+	key := s.activeKey()
+
+	switch {
+	case key == nil:
+		return nil, fmt.Errorf("%w: no active key", ErrMissingKey)
+	case key.Signing == nil:
+		return nil, fmt.Errorf("%w: no private key to sign %s tokens",
+			ErrMissingKey, key.Method.Alg())
+	default:
+		return key, nil
+	}
+}
+
+// verificationKey returns the key used to verify the given token. Tokens with
+// a kid header are verified using the matching key from the KeyRing, all other
+// tokens are verified using the active key.
+func (s *Signatory) verificationKey(token *jwt.Token) (any, error) {
+	id, _ := token.Header[keyIDHeader].(string)
+
+	if s.Source != nil {
+		if key, ok := s.Source.Lookup(id); ok {
+			return s.accept(token, key)
+		}
+
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+
+	key := s.activeKey()
+
+	if id != "" && s.Keys != nil {
+		var ok bool
+
+		if key, ok = s.Keys.Lookup(id); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+		}
+	}
+
+	return s.accept(token, key)
+}
+
+// accept the given token's algorithm for the given key, returning the key used
+// to verify the token.
+func (s *Signatory) accept(token *jwt.Token, key *Key) (any, error) {
+	if key == nil {
+		return nil, fmt.Errorf("%w: no active key", ErrMissingKey)
+	}
+
+	if len(s.Methods) > 0 && !contains(s.Methods, token.Method.Alg()) {
+		return nil, fmt.Errorf("%w: %s is not allowed", ErrWrongAlgorithm, token.Method.Alg())
+	}
+
+	if key.Method != nil && key.Method.Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("%w: %s token for %s key", ErrWrongAlgorithm,
+			token.Method.Alg(), key.Method.Alg())
+	}
+
+	return key.Verifying, nil
+}
+
+// publicKey returns the PublicKey, or the public half of PrivateKey if no
+// PublicKey is set. If neither are set nil is returned.
+func (s *Signatory) publicKey() crypto.PublicKey {
+	switch {
+	case s.PublicKey != nil:
+		return s.PublicKey
+	case s.PrivateKey != nil:
+		return s.PrivateKey.Public()
+	default:
+		return nil
+	}
+}
+
 // GenerateSecret will generate a new secret key. For most uses rand.Reader
 // should be used to ensure a cryptographically secure secret. GenerateSecret
 // will panic if it fails.
@@ -113,3 +615,28 @@ func GenerateSecret(reader io.Reader) string {
 
 	return base64.StdEncoding.EncodeToString(secret)
 }
+
+// GenerateID will generate a new random, URL safe identifier suitable for use
+// as a token ID. GenerateID will panic if it fails.
+func GenerateID() string {
+	const idLength = 16
+
+	id := make([]byte, idLength)
+
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+// contains returns true if the value is in the list.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
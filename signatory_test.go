@@ -2,10 +2,12 @@ package tonic_test
 
 import (
 	"crypto/rand"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/domdavis/tonic"
+	"github.com/domdavis/tonic/config"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
@@ -67,8 +69,232 @@ func TestSignatory_Validate(t *testing.T) {
 		t.Parallel()
 
 		s := &tonic.Signatory{}
-		r := s.Validate(&gin.Context{}, "")
+		err := s.Validate(&gin.Context{}, "")
 
-		assert.False(t, r)
+		assert.ErrorIs(t, err, tonic.ErrMissingToken)
+	})
+
+	t.Run("Validation errors give the reason for failure", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		token, err := s.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+
+		forged := &tonic.Signatory{Secret: "forged", TTL: time.Hour}
+		forgery, err := forged.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+
+		s.Methods = []string{"RS256"}
+
+		assert.ErrorIs(t, s.Validate(&gin.Context{}, token), tonic.ErrWrongAlgorithm)
+
+		s.Methods = nil
+
+		assert.ErrorIs(t, s.Validate(&gin.Context{}, "garbage"), tonic.ErrMalformed)
+		assert.ErrorIs(t, s.Validate(&gin.Context{}, forgery), tonic.ErrInvalidSignature)
+	})
+}
+
+func TestNewSignatory(t *testing.T) {
+	t.Run("Asymmetric keys can sign and validate tokens", func(t *testing.T) {
+		t.Parallel()
+
+		for _, name := range []string{"rsa", "ecdsa", "ed25519"} {
+			signer, err := tonic.NewSignatory(config.Security{
+				SessionTTL: time.Minute,
+				PrivateKey: fmt.Sprintf("testdata/%s.key", name),
+			})
+
+			assert.NoError(t, err)
+
+			verifier, err := tonic.NewSignatory(config.Security{
+				PublicKey: fmt.Sprintf("testdata/%s.pub", name),
+			})
+
+			assert.NoError(t, err)
+
+			token, err := signer.Sign(&gin.Context{})
+
+			assert.NoError(t, err)
+			assert.NoError(t, verifier.Validate(&gin.Context{}, token), name)
+			assert.NoError(t, signer.Validate(&gin.Context{}, token), name)
+		}
+	})
+
+	t.Run("Tokens signed with the secret will not validate against a key", func(t *testing.T) {
+		t.Parallel()
+
+		signer := &tonic.Signatory{Secret: "secret", TTL: time.Minute}
+		verifier, err := tonic.NewSignatory(config.Security{
+			Secret: "secret", PublicKey: "testdata/rsa.pub",
+		})
+
+		assert.NoError(t, err)
+
+		token, err := signer.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+		assert.Error(t, verifier.Validate(&gin.Context{}, token))
+	})
+
+	t.Run("A public key alone cannot sign", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := tonic.NewSignatory(config.Security{
+			SessionTTL: time.Minute, PublicKey: "testdata/rsa.pub",
+		})
+
+		assert.NoError(t, err)
+
+		_, err = s.Sign(&gin.Context{})
+
+		assert.ErrorIs(t, err, tonic.ErrMissingKey)
+	})
+
+	t.Run("Invalid key paths will error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := tonic.NewSignatory(config.Security{PrivateKey: "testdata/missing.key"})
+
+		assert.Error(t, err)
+
+		_, err = tonic.NewSignatory(config.Security{PublicKey: "testdata/missing.pub"})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Mismatched keys will error", func(t *testing.T) {
+		t.Parallel()
+
+		for private, public := range map[string]string{"rsa": "ecdsa", "ecdsa": "ed25519", "ed25519": "rsa"} {
+			_, err := tonic.NewSignatory(config.Security{
+				SessionTTL: time.Minute,
+				PrivateKey: fmt.Sprintf("testdata/%s.key", private),
+				PublicKey:  fmt.Sprintf("testdata/%s.pub", public),
+			})
+
+			assert.ErrorIs(t, err, tonic.ErrKeyMismatch, private)
+		}
+	})
+}
+
+func TestSignatory_JWKS(t *testing.T) {
+	t.Run("Signatories without a KeyRing publish their public key", func(t *testing.T) {
+		t.Parallel()
+
+		key, err := tonic.LoadPrivateKey("testdata/ed25519.key")
+
+		assert.NoError(t, err)
+
+		s := &tonic.Signatory{PrivateKey: key}
+		s.Initialise()
+
+		assert.Len(t, s.JWKS().Keys, 1)
+	})
+
+	t.Run("Signatories using a secret publish nothing", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret"}
+		s.Initialise()
+
+		assert.Empty(t, s.JWKS().Keys)
+	})
+}
+
+func TestSignatory_Source(t *testing.T) {
+	t.Run("Tokens are validated against the source", func(t *testing.T) {
+		t.Parallel()
+
+		signer, err := tonic.NewSignatory(config.Security{
+			PrivateKey: "testdata/rsa.key", SessionTTL: time.Minute,
+		})
+
+		assert.NoError(t, err)
+
+		token, err := signer.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+
+		s := &tonic.Signatory{Source: signer.Keys}
+
+		assert.NoError(t, s.Validate(&gin.Context{}, token))
+
+		s.Source = tonic.NewKeyRing(tonic.NewSecretKey("secret"), 0)
+
+		assert.Error(t, s.Validate(&gin.Context{}, token))
+	})
+}
+
+func TestSignatory_Type(t *testing.T) {
+	t.Run("Tokens of another type are rejected", func(t *testing.T) {
+		t.Parallel()
+
+		session := &tonic.Signatory{Secret: "secret", TTL: time.Minute, Type: tonic.SessionType}
+		access := &tonic.Signatory{Secret: "secret", TTL: time.Minute, Type: tonic.AccessType}
+
+		token, err := session.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+		assert.NoError(t, session.Validate(&gin.Context{}, token))
+		assert.Error(t, access.Validate(&gin.Context{}, token))
+
+		access.AcceptTypes = []string{tonic.SessionType}
+
+		assert.NoError(t, access.Validate(&gin.Context{}, token))
+	})
+
+	t.Run("Untyped tokens are rejected by typed signatories", func(t *testing.T) {
+		t.Parallel()
+
+		untyped := &tonic.Signatory{Secret: "secret", TTL: time.Minute}
+		typed := &tonic.Signatory{Secret: "secret", Type: tonic.SessionType}
+
+		token, err := untyped.Sign(&gin.Context{})
+
+		assert.NoError(t, err)
+		assert.Error(t, typed.Validate(&gin.Context{}, token))
+	})
+}
+
+func TestSignatory_Renew(t *testing.T) {
+	t.Run("Renewed tokens keep their private claims", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+		token, err := s.Issue(jwt.MapClaims{"user": "user", tonic.IDClaim: "id"})
+
+		assert.NoError(t, err)
+
+		renewed, err := s.Renew(token)
+
+		assert.NoError(t, err)
+
+		claims, err := s.Parse(renewed)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "user", claims["user"])
+		assert.NotEqual(t, "id", claims[tonic.IDClaim])
+	})
+
+	t.Run("Invalid tokens cannot be renewed", func(t *testing.T) {
+		t.Parallel()
+
+		s := &tonic.Signatory{Secret: "secret", TTL: time.Hour}
+
+		_, err := s.Renew("garbage")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestGenerateID(t *testing.T) {
+	t.Run("IDs are unique", func(t *testing.T) {
+		t.Parallel()
+
+		assert.NotEqual(t, tonic.GenerateID(), tonic.GenerateID())
 	})
 }
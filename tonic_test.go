@@ -71,7 +71,7 @@ func Example_tonic() {
 
 	// Output:
 	// 200 pong
-	// 307 <a href="/webapp/login">Temporary Redirect</a>.
+	// 307 <a href="/webapp/login?next=%2Fwebapp%2Fpage">Temporary Redirect</a>.
 	// 401 Unauthorized
 	// 200 OK
 	// 200 OK